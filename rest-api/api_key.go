@@ -0,0 +1,49 @@
+package todo
+
+import (
+	"fmt"
+	"time"
+
+	"akhmet.com/rest-api/pkg/validate"
+)
+
+type ApiKey struct {
+	Id         int        `json:"id" db:"id"`
+	UserId     int        `json:"-" db:"user_id"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"`
+	Scopes     Scopes     `json:"scopes" db:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at" db:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+type CreateApiKeyInput struct {
+	Name      string     `json:"name" validate:"trim,required,max=255,charset=text"`
+	Scopes    Scopes     `json:"scopes" validate:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// Check reports unknown scopes, scopes api keys cannot have and an expiry
+// in the past.
+func (i *CreateApiKeyInput) Check() validate.Errors {
+	var errs validate.Errors
+	for _, scope := range i.Scopes {
+		switch {
+		case !AllScopes.Has(scope):
+			errs = append(errs, validate.FieldError{Field: "scopes", Code: validate.CodeInvalidChoice, Message: fmt.Sprintf("unknown scope %q", scope)})
+		case !ApiKeyScopes.Has(scope):
+			errs = append(errs, validate.FieldError{Field: "scopes", Code: validate.CodeInvalidChoice, Message: fmt.Sprintf("scope %q is not allowed for api keys", scope)})
+		}
+	}
+
+	if i.ExpiresAt != nil && i.ExpiresAt.Before(time.Now()) {
+		errs = append(errs, validate.FieldError{Field: "expires_at", Code: validate.CodeInvalid, Message: "must be in the future"})
+	}
+
+	return errs
+}
+
+func (i *CreateApiKeyInput) Validate() error {
+	return validate.Struct(i)
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"akhmet.com/rest-api"
+)
+
+func (c *Client) AdminGetUsers(ctx context.Context, search string, limit, offset int) ([]todo.UserAccount, error) {
+	query := url.Values{}
+	if search != "" {
+		query.Set("search", search)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		query.Set("offset", strconv.Itoa(offset))
+	}
+
+	path := "/admin/users/"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var response struct {
+		Data []todo.UserAccount `json:"data"`
+	}
+	err := c.do(ctx, request{method: http.MethodGet, path: path}, &response)
+	return response.Data, err
+}
+
+func (c *Client) AdminDisableUser(ctx context.Context, userId int) error {
+	return c.do(ctx, request{method: http.MethodPost, path: fmt.Sprintf("/admin/users/%d/disable", userId)}, nil)
+}
+
+func (c *Client) AdminEnableUser(ctx context.Context, userId int) error {
+	return c.do(ctx, request{method: http.MethodPost, path: fmt.Sprintf("/admin/users/%d/enable", userId)}, nil)
+}
+
+func (c *Client) AdminForcePasswordReset(ctx context.Context, userId int) error {
+	return c.do(ctx, request{method: http.MethodPost, path: fmt.Sprintf("/admin/users/%d/force-password-reset", userId)}, nil)
+}
+
+func (c *Client) AdminGetUsage(ctx context.Context) ([]todo.UserUsage, error) {
+	var response struct {
+		Data []todo.UserUsage `json:"data"`
+	}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/admin/stats"}, &response)
+	return response.Data, err
+}
+
+func (c *Client) AdminTransferList(ctx context.Context, listId, userId int) error {
+	return c.do(ctx, request{
+		method: http.MethodPost,
+		path:   fmt.Sprintf("/admin/lists/%d/transfer", listId),
+		body:   todo.TransferListInput{UserId: userId},
+	}, nil)
+}
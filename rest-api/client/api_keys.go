@@ -0,0 +1,30 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"akhmet.com/rest-api"
+)
+
+// CreateApiKey returns the key metadata and the plain key, which the server
+// only reveals once.
+func (c *Client) CreateApiKey(ctx context.Context, input todo.CreateApiKeyInput) (todo.ApiKey, string, error) {
+	var response struct {
+		todo.ApiKey
+		Key string `json:"key"`
+	}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v2/api-keys/", body: input}, &response)
+	return response.ApiKey, response.Key, err
+}
+
+func (c *Client) GetApiKeys(ctx context.Context) ([]todo.ApiKey, error) {
+	var response []todo.ApiKey
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v2/api-keys/"}, &response)
+	return response, err
+}
+
+func (c *Client) DeleteApiKey(ctx context.Context, keyId int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: fmt.Sprintf("/api/v2/api-keys/%d", keyId)}, nil)
+}
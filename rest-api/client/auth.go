@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"akhmet.com/rest-api"
+)
+
+type SignInResult struct {
+	Token             string `json:"token"`
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+}
+
+type idResponse struct {
+	Id int `json:"id"`
+}
+
+type tokenResponse struct {
+	Token string `json:"token"`
+}
+
+func (c *Client) SignUp(ctx context.Context, user todo.User) (int, error) {
+	var response idResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/auth/sign-up", body: user, public: true}, &response)
+	return response.Id, err
+}
+
+// SignIn signs in and, unless a two-factor challenge is returned, makes the
+// issued token the one used by the client.
+func (c *Client) SignIn(ctx context.Context, username, password string) (SignInResult, error) {
+	result, err := c.signIn(ctx, username, password)
+	if err != nil {
+		return result, err
+	}
+
+	if result.Token != "" {
+		c.SetToken(result.Token)
+	}
+
+	return result, nil
+}
+
+func (c *Client) signIn(ctx context.Context, username, password string) (SignInResult, error) {
+	var result SignInResult
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/auth/sign-in",
+		body: map[string]string{
+			"username": username,
+			"password": password,
+		},
+		public: true,
+	}, &result)
+
+	return result, err
+}
+
+func (c *Client) SignInTwoFactor(ctx context.Context, challengeToken, code string) (string, error) {
+	var response tokenResponse
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/auth/sign-in/2fa",
+		body: map[string]string{
+			"challenge_token": challengeToken,
+			"code":            code,
+		},
+		public: true,
+	}, &response)
+	if err != nil {
+		return "", err
+	}
+
+	c.SetToken(response.Token)
+	return response.Token, nil
+}
+
+// ChangePassword needs a TOTP or recovery code in code when the account has
+// two-factor authentication enabled; otherwise code is left empty.
+func (c *Client) ChangePassword(ctx context.Context, username, password, newPassword, code string) error {
+	return c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/auth/change-password",
+		body: map[string]string{
+			"username":     username,
+			"password":     password,
+			"new_password": newPassword,
+			"code":         code,
+		},
+		public: true,
+	}, nil)
+}
+
+// ExchangeToken returns a new token limited to the given scopes. The
+// client keeps using its current token.
+func (c *Client) ExchangeToken(ctx context.Context, scopes todo.Scopes) (string, error) {
+	var response tokenResponse
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/v2/tokens",
+		body:   map[string]todo.Scopes{"scopes": scopes},
+	}, &response)
+
+	return response.Token, err
+}
+
+func (c *Client) EnrollTwoFactor(ctx context.Context) (todo.TOTPEnrollment, error) {
+	var enrollment todo.TOTPEnrollment
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v2/2fa/enroll"}, &enrollment)
+	return enrollment, err
+}
+
+func (c *Client) ConfirmTwoFactor(ctx context.Context, code string) ([]string, error) {
+	var response struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/v2/2fa/confirm",
+		body:   map[string]string{"code": code},
+	}, &response)
+
+	return response.RecoveryCodes, err
+}
@@ -0,0 +1,349 @@
+// Package client is a typed Go client for the todo REST API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultBackoff    = 200 * time.Millisecond
+	workspaceHeader   = "X-Workspace-Id"
+	mergePatchType    = "application/merge-patch+json"
+	idempotencyHeader = "Idempotency-Key"
+	requestIDHeader   = "X-Request-ID"
+
+	// apiPrefix is the API version the client speaks. Its responses wrap
+	// the payload in a data envelope which send unwraps.
+	apiPrefix = "/api/v2/"
+)
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	maxRetries int
+	backoff    time.Duration
+	workspace  int
+	auth       *authState
+}
+
+// authState is shared between a client and the copies made by WithWorkspace
+// so that a refreshed token is seen by all of them.
+type authState struct {
+	mu       sync.Mutex
+	token    string
+	username string
+	password string
+}
+
+type Option func(*Client)
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithToken authenticates requests with a JWT or an api key.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.auth.token = token
+	}
+}
+
+// WithCredentials signs in lazily and signs in again whenever the server
+// rejects the current token, e.g. after it expired.
+func WithCredentials(username, password string) Option {
+	return func(c *Client) {
+		c.auth.username = username
+		c.auth.password = password
+	}
+}
+
+// WithRetries sets how many times idempotent requests are retried on
+// network errors and 429/502/503/504 responses.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+	}
+}
+
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		maxRetries: defaultMaxRetries,
+		backoff:    defaultBackoff,
+		auth:       &authState{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// WithWorkspace returns a client whose list and item calls operate in the
+// given workspace.
+func (c *Client) WithWorkspace(workspaceId int) *Client {
+	copied := *c
+	copied.workspace = workspaceId
+	return &copied
+}
+
+// Token returns the token currently used for requests.
+func (c *Client) Token() string {
+	c.auth.mu.Lock()
+	defer c.auth.mu.Unlock()
+
+	return c.auth.token
+}
+
+func (c *Client) SetToken(token string) {
+	c.auth.mu.Lock()
+	defer c.auth.mu.Unlock()
+
+	c.auth.token = token
+}
+
+func (c *Client) hasCredentials() bool {
+	return c.auth.username != "" && c.auth.password != ""
+}
+
+// authorize returns the token to use, signing in first when only
+// credentials are configured.
+func (c *Client) authorize(ctx context.Context) (string, error) {
+	token := c.Token()
+	if token != "" || !c.hasCredentials() {
+		return token, nil
+	}
+
+	return c.refresh(ctx, "")
+}
+
+// refresh signs in again unless another goroutine already replaced the
+// token that was rejected.
+func (c *Client) refresh(ctx context.Context, rejected string) (string, error) {
+	c.auth.mu.Lock()
+	defer c.auth.mu.Unlock()
+
+	if c.auth.token != "" && c.auth.token != rejected {
+		return c.auth.token, nil
+	}
+
+	result, err := c.signIn(ctx, c.auth.username, c.auth.password)
+	if err != nil {
+		return "", err
+	}
+
+	if result.TwoFactorRequired {
+		return "", errors.New("client: account requires two-factor sign-in, use a token or api key instead")
+	}
+
+	c.auth.token = result.Token
+	return c.auth.token, nil
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context whose requests carry id as X-Request-ID,
+// so they can be found in the server logs under the caller's own id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+type request struct {
+	method string
+	path   string
+	body   interface{}
+	public bool
+
+	// contentType overrides application/json for the request body.
+	contentType string
+	// idempotencyKey is sent as Idempotency-Key and makes a POST safe to
+	// retry, since the server replays the first response.
+	idempotencyKey string
+}
+
+func (c *Client) do(ctx context.Context, req request, out interface{}) error {
+	var body []byte
+	if req.body != nil {
+		var err error
+		body, err = json.Marshal(req.body)
+		if err != nil {
+			return err
+		}
+	}
+
+	var token string
+	if !req.public {
+		var err error
+		token, err = c.authorize(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := c.doWithRetries(ctx, req, body, token, out)
+
+	var apiErr *Error
+	if !req.public && c.hasCredentials() && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+		token, err = c.refresh(ctx, token)
+		if err != nil {
+			return err
+		}
+		return c.doWithRetries(ctx, req, body, token, out)
+	}
+
+	return err
+}
+
+func (c *Client) doWithRetries(ctx context.Context, req request, body []byte, token string, out interface{}) error {
+	attempts := 1
+	if isIdempotent(req.method) || req.idempotencyKey != "" {
+		attempts += c.maxRetries
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(c.backoff << (attempt - 1))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		var retry bool
+		retry, err = c.send(ctx, req, body, token, out)
+		if !retry {
+			return err
+		}
+	}
+
+	return err
+}
+
+// send performs a single attempt and reports whether it may be retried.
+func (c *Client) send(ctx context.Context, req request, body []byte, token string, out interface{}) (bool, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, c.baseURL+req.path, reader)
+	if err != nil {
+		return false, err
+	}
+
+	httpReq.Header.Set("Accept", "application/json")
+	if body != nil {
+		contentType := req.contentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+	if token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+	if c.workspace != 0 {
+		httpReq.Header.Set(workspaceHeader, strconv.Itoa(c.workspace))
+	}
+	if req.idempotencyKey != "" {
+		httpReq.Header.Set(idempotencyHeader, req.idempotencyKey)
+	}
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok && id != "" {
+		httpReq.Header.Set(requestIDHeader, id)
+	}
+	// Continues the caller's trace when the application has installed a
+	// propagator, such as W3C trace context.
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(httpReq.Header))
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return true, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := newError(resp.StatusCode, data)
+		apiErr.RequestID = resp.Header.Get(requestIDHeader)
+		return isRetryableStatus(resp.StatusCode), apiErr
+	}
+
+	if out == nil || len(data) == 0 {
+		return false, nil
+	}
+
+	if strings.HasPrefix(req.path, apiPrefix) {
+		var envelope struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(data, &envelope); err != nil {
+			return false, fmt.Errorf("client: decoding %s %s response: %w", req.method, req.path, err)
+		}
+		data = envelope.Data
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return false, fmt.Errorf("client: decoding %s %s response: %w", req.method, req.path, err)
+	}
+
+	return false, nil
+}
+
+func newIdempotencyKey() (string, error) {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(key), nil
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,333 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/handler"
+	"akhmet.com/rest-api/pkg/service"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// fakeAuth issues numbered tokens on sign-in. Revoking makes the server
+// reject every token issued so far, as if they had expired.
+type fakeAuth struct {
+	service.Authorization
+
+	mu      sync.Mutex
+	signIns int
+	valid   map[string]todo.Scopes
+}
+
+func (a *fakeAuth) Authenticate(ctx context.Context, username, password string) (todo.User, error) {
+	if username != "alice" || password != "secret" {
+		return todo.User{}, sql.ErrNoRows
+	}
+	return todo.User{Id: 1}, nil
+}
+
+func (a *fakeAuth) GenerateToken(ctx context.Context, userId int, scopes todo.Scopes) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.signIns++
+	token := "token-" + strconv.Itoa(a.signIns)
+	a.valid[token] = scopes
+	return token, nil
+}
+
+func (a *fakeAuth) ParseToken(ctx context.Context, token string) (todo.Principal, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	scopes, ok := a.valid[token]
+	if !ok {
+		return todo.Principal{}, errors.New("token is expired")
+	}
+	return todo.Principal{UserId: 1, Role: todo.RoleAdmin, Scopes: scopes}, nil
+}
+
+func (a *fakeAuth) revoke() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.valid = map[string]todo.Scopes{}
+}
+
+type fakeApiKeys struct {
+	service.ApiKey
+}
+
+func (fakeApiKeys) IsApiKey(token string) bool {
+	return false
+}
+
+type fakeWorkspaces struct {
+	service.Workspace
+}
+
+func (fakeWorkspaces) GetPersonal(ctx context.Context, userId int) (todo.Workspace, error) {
+	return todo.Workspace{Id: 1, Personal: true}, nil
+}
+
+func (fakeWorkspaces) GetById(ctx context.Context, userId, workspaceId int) (todo.Workspace, error) {
+	return todo.Workspace{}, sql.ErrNoRows
+}
+
+func (fakeWorkspaces) RemoveMember(ctx context.Context, userId, workspaceId, memberId int) error {
+	return sql.ErrNoRows
+}
+
+// fakeLists blocks GetAll while block is set, until the request is
+// cancelled.
+type fakeLists struct {
+	service.TodoList
+	block   bool
+	started chan struct{}
+}
+
+func (l *fakeLists) Create(ctx context.Context, userId, workspaceId int, list todo.TodoList) (int, error) {
+	return 7, nil
+}
+
+func (l *fakeLists) GetAll(ctx context.Context, userId, workspaceId int) ([]todo.TodoList, error) {
+	if l.block {
+		close(l.started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return []todo.TodoList{{Id: 7, Title: "groceries"}}, nil
+}
+
+type fakeIdempotency struct {
+	service.Idempotency
+}
+
+func (fakeIdempotency) Begin(ctx context.Context, userId int, key, fingerprint string) (*todo.IdempotencyRecord, error) {
+	return nil, nil
+}
+
+func (fakeIdempotency) Complete(ctx context.Context, userId int, key string, statusCode int, contentType string, body []byte) error {
+	return nil
+}
+
+func (fakeIdempotency) Abort(ctx context.Context, userId int, key string) error {
+	return nil
+}
+
+type fakeAdmin struct {
+	service.Admin
+}
+
+func (fakeAdmin) SetDisabled(ctx context.Context, userId int, disabled bool) error {
+	return nil
+}
+
+// testServer serves handler.InitRoutes. Paths in failFirst answer their
+// first request with 503, like an overloaded proxy in front of the api.
+type testServer struct {
+	*httptest.Server
+	auth  *fakeAuth
+	lists *fakeLists
+
+	mu        sync.Mutex
+	failFirst map[string]bool
+	attempts  map[string]int
+	keys      map[string][]string
+}
+
+func newTestServer(t *testing.T, failFirst ...string) *testServer {
+	s := &testServer{
+		auth:      &fakeAuth{valid: map[string]todo.Scopes{}},
+		lists:     &fakeLists{started: make(chan struct{})},
+		failFirst: map[string]bool{},
+		attempts:  map[string]int{},
+		keys:      map[string][]string{},
+	}
+	for _, route := range failFirst {
+		s.failFirst[route] = true
+	}
+
+	router := handler.NewHandler(&service.Service{
+		Authorization: s.auth,
+		ApiKey:        fakeApiKeys{},
+		Admin:         fakeAdmin{},
+		Workspace:     fakeWorkspaces{},
+		Idempotency:   fakeIdempotency{},
+		TodoList:      s.lists,
+	}).InitRoutes()
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.Method + " " + r.URL.Path
+
+		s.mu.Lock()
+		s.attempts[route]++
+		s.keys[route] = append(s.keys[route], r.Header.Get(idempotencyHeader))
+		fail := s.failFirst[route] && s.attempts[route] == 1
+		s.mu.Unlock()
+
+		if fail {
+			http.Error(w, `{"message":"try again"}`, http.StatusServiceUnavailable)
+			return
+		}
+		router.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *testServer) attemptsOf(route string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts[route]
+}
+
+func (s *testServer) keysOf(route string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keys[route]
+}
+
+func TestClientSignsInAndRefreshesToken(t *testing.T) {
+	s := newTestServer(t)
+	c := New(s.URL, WithCredentials("alice", "secret"))
+	ctx := context.Background()
+
+	if _, err := c.GetLists(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if token := c.Token(); token != "token-1" {
+		t.Fatalf("token %q after signing in, want token-1", token)
+	}
+
+	s.auth.revoke()
+
+	lists, err := c.GetLists(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lists) != 1 || lists[0].Title != "groceries" {
+		t.Fatalf("unexpected lists %+v", lists)
+	}
+	if token := c.Token(); token != "token-2" {
+		t.Fatalf("token %q after refresh, want token-2", token)
+	}
+	if n := s.attemptsOf("POST /auth/sign-in"); n != 2 {
+		t.Fatalf("signed in %d times, want 2", n)
+	}
+}
+
+func TestClientWithoutCredentialsDoesNotRefresh(t *testing.T) {
+	s := newTestServer(t)
+	c := New(s.URL, WithToken("expired"))
+
+	if _, err := c.GetLists(context.Background()); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("got %v, want %v", err, ErrUnauthorized)
+	}
+	if n := s.attemptsOf("POST /auth/sign-in"); n != 0 {
+		t.Fatalf("signed in %d times without credentials", n)
+	}
+}
+
+func TestClientDecodesErrors(t *testing.T) {
+	s := newTestServer(t)
+	token, _ := s.auth.GenerateToken(context.Background(), 1, todo.Scopes{todo.ScopeListsRead, todo.ScopeListsWrite, todo.ScopeAccount})
+	readOnly, _ := s.auth.GenerateToken(context.Background(), 1, todo.Scopes{todo.ScopeListsRead})
+	ctx := context.Background()
+
+	var apiErr *Error
+
+	_, err := New(s.URL, WithToken(readOnly)).CreateList(ctx, todo.TodoList{Title: "groceries"})
+	if !errors.Is(err, ErrForbidden) || !errors.As(err, &apiErr) || len(apiErr.MissingScopes) != 1 || apiErr.MissingScopes[0] != todo.ScopeListsWrite {
+		t.Fatalf("missing scope: got %#v", err)
+	}
+
+	_, err = New(s.URL, WithToken(token)).CreateList(ctx, todo.TodoList{})
+	if !errors.Is(err, ErrInvalid) || !errors.As(err, &apiErr) || len(apiErr.FieldErrors) == 0 || apiErr.FieldErrors[0].Field != "title" {
+		t.Fatalf("validation: got %#v", err)
+	}
+
+	err = New(s.URL, WithToken(token)).RemoveWorkspaceMember(ctx, 3, 4)
+	if !errors.Is(err, ErrNotFound) || errors.Is(err, ErrForbidden) {
+		t.Fatalf("not found: got %#v", err)
+	}
+}
+
+func TestClientRetriesOnlyIdempotentCalls(t *testing.T) {
+	s := newTestServer(t,
+		"GET /api/v2/lists/",
+		"POST /api/v2/lists/",
+		"POST /admin/users/5/disable",
+	)
+	token, _ := s.auth.GenerateToken(context.Background(), 1, todo.AllScopes)
+	c := New(s.URL, WithToken(token), WithRetries(2, time.Millisecond))
+	ctx := context.Background()
+
+	if _, err := c.GetLists(ctx); err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	if n := s.attemptsOf("GET /api/v2/lists/"); n != 2 {
+		t.Fatalf("GET sent %d times, want 2", n)
+	}
+
+	id, err := c.CreateList(ctx, todo.TodoList{Title: "groceries"})
+	if err != nil || id != 7 {
+		t.Fatalf("POST with idempotency key: got %d, %v", id, err)
+	}
+	keys := s.keysOf("POST /api/v2/lists/")
+	if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Fatalf("POST with idempotency key sent with keys %q, want the same key twice", keys)
+	}
+
+	if err := c.AdminDisableUser(ctx, 5); err == nil {
+		t.Fatal("POST without idempotency key succeeded on retry")
+	}
+	if n := s.attemptsOf("POST /admin/users/5/disable"); n != 1 {
+		t.Fatalf("POST without idempotency key sent %d times, want 1", n)
+	}
+}
+
+func TestClientStopsOnContextCancel(t *testing.T) {
+	s := newTestServer(t)
+	token, _ := s.auth.GenerateToken(context.Background(), 1, todo.AllScopes)
+	c := New(s.URL, WithToken(token), WithRetries(3, time.Millisecond))
+
+	s.lists.block = true
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-s.lists.started
+		cancel()
+	}()
+
+	if _, err := c.GetLists(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	if n := s.attemptsOf("GET /api/v2/lists/"); n != 1 {
+		t.Fatalf("cancelled request sent %d times, want 1", n)
+	}
+}
+
+func TestClientStopsBackingOffOnContextCancel(t *testing.T) {
+	s := newTestServer(t, "GET /api/v2/lists/")
+	token, _ := s.auth.GenerateToken(context.Background(), 1, todo.AllScopes)
+	c := New(s.URL, WithToken(token), WithRetries(3, time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.GetLists(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
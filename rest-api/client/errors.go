@@ -0,0 +1,96 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrInvalid      = errors.New("unprocessable entity")
+)
+
+// FieldError describes why the server rejected a single request field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is returned for every non-2xx response. It matches the sentinel
+// errors above with errors.Is according to its status code.
+type Error struct {
+	StatusCode    int
+	Message       string
+	MissingScopes []string
+	FieldErrors   []FieldError
+
+	// RequestID identifies the failed request in the server logs.
+	RequestID string
+}
+
+func (e *Error) Error() string {
+	message := e.Message
+	if message == "" {
+		message = http.StatusText(e.StatusCode)
+	}
+
+	if len(e.MissingScopes) > 0 {
+		return "todo api: " + message + " (missing " + strings.Join(e.MissingScopes, ", ") + ")"
+	}
+
+	if len(e.FieldErrors) > 0 {
+		fields := make([]string, 0, len(e.FieldErrors))
+		for _, fieldError := range e.FieldErrors {
+			fields = append(fields, fieldError.Field+": "+fieldError.Message)
+		}
+		return "todo api: " + message + " (" + strings.Join(fields, "; ") + ")"
+	}
+
+	return "todo api: " + message
+}
+
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrInvalid:
+		return e.StatusCode == http.StatusUnprocessableEntity
+	default:
+		return false
+	}
+}
+
+type errorResponse struct {
+	Message       string       `json:"message"`
+	MissingScopes []string     `json:"missing_scopes"`
+	Errors        []FieldError `json:"errors"`
+}
+
+func newError(statusCode int, body []byte) *Error {
+	var response errorResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		response.Message = strings.TrimSpace(string(body))
+	}
+
+	return &Error{
+		StatusCode:    statusCode,
+		Message:       response.Message,
+		MissingScopes: response.MissingScopes,
+		FieldErrors:   response.Errors,
+	}
+}
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"akhmet.com/rest-api"
+)
+
+func (c *Client) CreateItem(ctx context.Context, listId int, item todo.TodoItem) (int, error) {
+	key, err := newIdempotencyKey()
+	if err != nil {
+		return 0, err
+	}
+
+	var response idResponse
+	err = c.do(ctx, request{method: http.MethodPost, path: fmt.Sprintf("/api/v2/lists/%d/items/", listId), body: item, idempotencyKey: key}, &response)
+	return response.Id, err
+}
+
+func (c *Client) GetItems(ctx context.Context, listId int) ([]todo.TodoItem, error) {
+	var items []todo.TodoItem
+	err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/api/v2/lists/%d/items/", listId)}, &items)
+	return items, err
+}
+
+func (c *Client) GetItem(ctx context.Context, itemId int) (todo.TodoItem, error) {
+	var item todo.TodoItem
+	err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/api/v2/items/%d", itemId)}, &item)
+	return item, err
+}
+
+func (c *Client) UpdateItem(ctx context.Context, itemId int, input todo.UpdateItemInput) error {
+	return c.do(ctx, request{method: http.MethodPut, path: fmt.Sprintf("/api/v2/items/%d", itemId), body: input}, nil)
+}
+
+func (c *Client) DeleteItem(ctx context.Context, itemId int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: fmt.Sprintf("/api/v2/items/%d", itemId)}, nil)
+}
+
+// MergePatchItem applies a JSON Merge Patch to the item and returns the
+// result. A nil value for "description" clears it.
+func (c *Client) MergePatchItem(ctx context.Context, itemId int, patch map[string]interface{}) (todo.TodoItem, error) {
+	var item todo.TodoItem
+	err := c.do(ctx, request{method: http.MethodPatch, path: fmt.Sprintf("/api/v2/items/%d", itemId), body: patch, contentType: mergePatchType}, &item)
+	return item, err
+}
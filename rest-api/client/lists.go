@@ -0,0 +1,48 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"akhmet.com/rest-api"
+)
+
+func (c *Client) CreateList(ctx context.Context, list todo.TodoList) (int, error) {
+	key, err := newIdempotencyKey()
+	if err != nil {
+		return 0, err
+	}
+
+	var response idResponse
+	err = c.do(ctx, request{method: http.MethodPost, path: "/api/v2/lists/", body: list, idempotencyKey: key}, &response)
+	return response.Id, err
+}
+
+func (c *Client) GetLists(ctx context.Context) ([]todo.TodoList, error) {
+	var response []todo.TodoList
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v2/lists/"}, &response)
+	return response, err
+}
+
+func (c *Client) GetList(ctx context.Context, listId int) (todo.TodoList, error) {
+	var list todo.TodoList
+	err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/api/v2/lists/%d", listId)}, &list)
+	return list, err
+}
+
+func (c *Client) UpdateList(ctx context.Context, listId int, input todo.UpdateListInput) error {
+	return c.do(ctx, request{method: http.MethodPut, path: fmt.Sprintf("/api/v2/lists/%d", listId), body: input}, nil)
+}
+
+func (c *Client) DeleteList(ctx context.Context, listId int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: fmt.Sprintf("/api/v2/lists/%d", listId)}, nil)
+}
+
+// MergePatchList applies a JSON Merge Patch to the list and returns the
+// result. A nil value for "description" clears it.
+func (c *Client) MergePatchList(ctx context.Context, listId int, patch map[string]interface{}) (todo.TodoList, error) {
+	var list todo.TodoList
+	err := c.do(ctx, request{method: http.MethodPatch, path: fmt.Sprintf("/api/v2/lists/%d", listId), body: patch, contentType: mergePatchType}, &list)
+	return list, err
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"akhmet.com/rest-api"
+)
+
+func (c *Client) CreateWorkspace(ctx context.Context, name string) (int, error) {
+	var response idResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v2/workspaces/", body: todo.Workspace{Name: name}}, &response)
+	return response.Id, err
+}
+
+func (c *Client) GetWorkspaces(ctx context.Context) ([]todo.Workspace, error) {
+	var response []todo.Workspace
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v2/workspaces/"}, &response)
+	return response, err
+}
+
+func (c *Client) GetWorkspaceMembers(ctx context.Context, workspaceId int) ([]todo.WorkspaceMember, error) {
+	var response []todo.WorkspaceMember
+	err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/api/v2/workspaces/%d/members", workspaceId)}, &response)
+	return response, err
+}
+
+func (c *Client) AddWorkspaceMember(ctx context.Context, workspaceId int, input todo.AddWorkspaceMemberInput) error {
+	return c.do(ctx, request{method: http.MethodPost, path: fmt.Sprintf("/api/v2/workspaces/%d/members", workspaceId), body: input}, nil)
+}
+
+func (c *Client) RemoveWorkspaceMember(ctx context.Context, workspaceId, userId int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: fmt.Sprintf("/api/v2/workspaces/%d/members/%d", workspaceId, userId)}, nil)
+}
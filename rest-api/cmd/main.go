@@ -2,74 +2,371 @@ package main
 
 import (
 	"context"
+	"errors"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
+	"akhmet.com/rest-api/pkg/config"
 	"akhmet.com/rest-api/pkg/handler"
+	"akhmet.com/rest-api/pkg/logging"
+	"akhmet.com/rest-api/pkg/metrics"
+	"akhmet.com/rest-api/pkg/oidc"
 	"akhmet.com/rest-api/pkg/repository"
+	"akhmet.com/rest-api/pkg/rpc"
 	"akhmet.com/rest-api/pkg/service"
+	"akhmet.com/rest-api/pkg/tracing"
 	"akhmet.com/rest-api"
-	"github.com/joho/godotenv"
+	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	"github.com/sirupsen/logrus"
-	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// configWatchInterval is how often the config file is checked for changes.
+const configWatchInterval = 10 * time.Second
+
+// Build information, set with
+// go build -ldflags "-X main.version=v1.2.0 -X main.commit=$(git rev-parse HEAD) -X main.builtAt=$(date -u +%FT%TZ)"
+var (
+	version = "dev"
+	commit  = "unknown"
+	builtAt = "unknown"
 )
 
 func main() {
 	logrus.SetFormatter(new(logrus.JSONFormatter))
+	logrus.AddHook(logging.RedactHook{})
 
-	if err := initConfig(); err != nil {
+	loader := config.NewLoader("")
+	cfg, err := loader.Load()
+	if err != nil {
 		logrus.Fatalf("error initializing configs: %s", err.Error())
 	}
+	applyLogLevel(cfg)
 
-	if err := godotenv.Load(); err != nil {
-		logrus.Fatalf("error loading env variables: %s", err.Error())
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+		ServiceName: "todo-app",
+	})
+	if err != nil {
+		logrus.Fatalf("failed to initialize tracing: %s", err.Error())
 	}
 
-	db, err := repository.NewPostgresDB(repository.Config{
-		Host:     viper.GetString("db.host"),
-		Port:     viper.GetString("db.port"),
-		Username: viper.GetString("db.username"),
-		DBName:   viper.GetString("db.dbname"),
-		SSLMode:  viper.GetString("db.sslmode"),
-		Password: os.Getenv("DB_PASSWORD"),
-	})
+	dbConfig := repository.Config{
+		Host:            cfg.DB.Host,
+		Port:            cfg.DB.Port,
+		Username:        cfg.DB.Username,
+		DBName:          cfg.DB.DBName,
+		SSLMode:         cfg.DB.SSLMode,
+		Password:        cfg.DB.Password,
+		MaxOpenConns:    cfg.DB.MaxOpenConns,
+		MaxIdleConns:    cfg.DB.MaxIdleConns,
+		ConnMaxLifetime: cfg.DB.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.DB.ConnMaxIdleTime,
+		ConnectTimeout:  cfg.DB.ConnectTimeout,
+	}
+	db, err := repository.NewPostgresDB(context.Background(), dbConfig)
 
 	if err != nil {
 		logrus.Fatalf("fataled to initialize db: %s", err.Error())
 	}
 
-	repos := repository.NewRepository(db)
-	services := service.NewService(repos)
-	handlers := handler.NewHandler(services)
+	var replica *sqlx.DB
+	if cfg.DB.Replica.Host != "" {
+		replicaConfig := dbConfig
+		replicaConfig.Host = cfg.DB.Replica.Host
+		replicaConfig.Port = cfg.DB.Replica.Port
+
+		replica, err = repository.NewPostgresDB(context.Background(), replicaConfig)
+		if err != nil {
+			logrus.Fatalf("failed to initialize db replica: %s", err.Error())
+		}
+	}
+
+	keys, err := initKeys(cfg.Auth, cfg.Development())
+	if err != nil {
+		logrus.Fatalf("failed to load signing keys: %s", err.Error())
+	}
+
+	provider, err := initOIDCProvider(cfg.OIDC)
+	if err != nil {
+		logrus.Fatalf("failed to initialize oidc provider: %s", err.Error())
+	}
+
+	if cfg.Auth.PasswordSalt == "" {
+		logrus.Warn("no password salt configured, which is only allowed in development")
+	}
+
+	repos := repository.NewRepository(db, replica)
+	services := service.NewService(repos, keys, provider, service.AuthConfig{
+		PasswordSalt: cfg.Auth.PasswordSalt,
+		TokenTTL:     cfg.Auth.TokenTTL,
+	}, todo.BuildInfo{
+		Version: version,
+		Commit:  commit,
+		BuiltAt: builtAt,
+	})
+
+	var handlerOpts []handler.Option
+	var metricsServer *http.Server
+	if cfg.Metrics.Enabled {
+		metrics.RegisterDB(db.DB, cfg.DB.DBName)
+		if replica != nil {
+			metrics.RegisterDB(replica.DB, cfg.DB.DBName+"-replica")
+		}
+
+		if port := cfg.Metrics.Port; port != "" {
+			metricsServer = runMetricsServer(port)
+		} else {
+			handlerOpts = append(handlerOpts, handler.WithMetrics(metrics.Handler()))
+		}
+	}
+
+	handlerOpts = append(handlerOpts,
+		handler.WithV1Deprecation(cfg.API.V1Deprecation()),
+		handler.WithRateLimit(rateLimit(cfg)),
+		handler.WithFeatures(features(cfg)))
+	handlers := handler.NewHandler(services, handlerOpts...)
+
+	srv, err := todo.NewServer(todo.ServerConfig{
+		Port:              cfg.Port,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		DrainPeriod:       cfg.Server.DrainPeriod,
+		ShutdownTimeout:   cfg.Server.ShutdownTimeout,
+		TLS: todo.TLSConfig{
+			CertFile:       cfg.Server.TLS.CertFile,
+			KeyFile:        cfg.Server.TLS.KeyFile,
+			MinVersion:     cfg.Server.TLS.MinVersion,
+			CipherPolicy:   cfg.Server.TLS.CipherPolicy,
+			ClientCAFile:   cfg.Server.TLS.ClientCAFile,
+			ClientAuth:     cfg.Server.TLS.ClientAuth,
+			ReloadInterval: cfg.Server.TLS.ReloadInterval,
+			OnReload:       logCertificateReload,
+		},
+	}, handlers.InitRoutes())
+	if err != nil {
+		logrus.Fatalf("failed to configure http server: %s", err.Error())
+	}
+
+	// Hooks run in reverse order: the grpc and metrics servers stop first,
+	// the database is closed and traces are flushed last.
+	srv.OnDrain(services.Health.BeginShutdown)
+	srv.OnShutdown("tracing", shutdownTracing)
+	srv.OnShutdown("database", func(context.Context) error {
+		return db.Close()
+	})
+	if replica != nil {
+		srv.OnShutdown("database replica", func(context.Context) error {
+			return replica.Close()
+		})
+	}
+	if metricsServer != nil {
+		srv.OnShutdown("metrics server", metricsServer.Shutdown)
+	}
 
-	srv := new(todo.Server)
 	go func() {
-		if err := srv.Run(viper.GetString("port"), handlers.InitRoutes()); err != nil {
+		if err := srv.Run(); err != nil {
 			logrus.Fatalf("error occured while running http server: %s", err.Error())
 		}
 	}()
 
+	// grpc uses the certificates of the http server, reloads included.
+	var grpcOpts []grpc.ServerOption
+	if tlsConfig := srv.TLSConfig(); tlsConfig != nil {
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	grpcServer := rpc.NewServer(services, grpcOpts...)
+	srv.OnShutdown("grpc server", func(ctx context.Context) error {
+		return gracefulStop(ctx, grpcServer)
+	})
+	go func() {
+		listener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+		if err != nil {
+			logrus.Fatalf("error occured while listening for grpc: %s", err.Error())
+		}
+
+		if err := grpcServer.Serve(listener); err != nil {
+			logrus.Fatalf("error occured while running grpc server: %s", err.Error())
+		}
+	}()
+
 	logrus.Print("TodoApp Started")
 
+	stopWatch := make(chan struct{})
+	applyConfig := func(old, cfg *config.Config, err error) {
+		reloadConfig(handlers, old, cfg, err)
+	}
+	go loader.Watch(stopWatch, configWatchInterval, applyConfig)
+
+	// SIGHUP reloads the configuration and the tls certificates.
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			applyConfig(loader.Reload())
+			if cfg.Server.TLS.CertFile != "" {
+				srv.ReloadCertificates()
+			}
+		}
+	}()
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
 	<- quit
 
 	logrus.Print("TodoApp Shutting Down")
+	close(stopWatch)
 
-	if err := srv.Shutdown(context.Background()); err != nil {
+	// A second signal skips draining and waiting for requests.
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-quit
+		cancel()
+	}()
+
+	if err := srv.Shutdown(ctx); err != nil {
 		logrus.Errorf("error occured on server shutting down: %s", err.Error())
 	}
 
-	if err := db.Close(); err != nil {
-		logrus.Errorf("error occured on db connection close: %s", err.Error())
+	logrus.Print("TodoApp Stopped")
+}
+
+func logCertificateReload(err error) {
+	if err != nil {
+		logrus.Errorf("failed to reload tls certificates, keeping the previous ones: %s", err.Error())
+		return
+	}
+
+	logrus.Print("tls certificates reloaded")
+}
+
+// gracefulStop waits for running grpc calls to finish and stops the server
+// hard when ctx is done first.
+func gracefulStop(ctx context.Context, server *grpc.Server) error {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		server.Stop()
+		return ctx.Err()
+	}
+}
+
+// runMetricsServer serves /metrics on a port of its own, so it can be kept
+// off the network the api is exposed on.
+func runMetricsServer(port string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+
+	server := &http.Server{Addr: ":" + port, Handler: mux}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logrus.Fatalf("error occured while running metrics server: %s", err.Error())
+		}
+	}()
+
+	return server
+}
+
+// initKeys only falls back to an ephemeral key in development, where losing
+// every token on restart is acceptable.
+func initKeys(cfg config.Auth, development bool) (*service.KeySet, error) {
+	if len(cfg.Keys) == 0 {
+		if !development {
+			return nil, errors.New("auth.keys are required outside development")
+		}
+		logrus.Warn("no signing keys configured, using an ephemeral key")
+		return service.NewEphemeralKeySet()
+	}
+
+	keys := make([]service.KeyConfig, len(cfg.Keys))
+	for i, key := range cfg.Keys {
+		keys[i] = service.KeyConfig{
+			Id:             key.Id,
+			Algorithm:      key.Algorithm,
+			PrivateKeyFile: key.PrivateKeyFile,
+			PrivateKeyEnv:  key.PrivateKeyEnv,
+			PublicKeyFile:  key.PublicKeyFile,
+			PublicKeyEnv:   key.PublicKeyEnv,
+		}
+	}
+
+	return service.NewKeySet(keys, cfg.SigningKeyId)
+}
+
+func initOIDCProvider(cfg config.OIDC) (*oidc.Provider, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return oidc.NewProvider(ctx, oidc.Config{
+		Issuer:       cfg.Issuer,
+		ClientId:     cfg.ClientId,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.RedirectURL,
+		Scopes:       cfg.Scopes,
+	})
+}
+
+// reloadConfig applies a reloaded configuration. The log level, rate limit
+// and features change at runtime; other sections are reported as needing a
+// restart.
+func reloadConfig(handlers *handler.Handler, old, cfg *config.Config, err error) {
+	if err != nil {
+		logrus.Errorf("config reload rejected, keeping the current config: %s", err.Error())
+		return
+	}
+
+	applyLogLevel(cfg)
+	if cfg.RateLimit != old.RateLimit {
+		handlers.SetRateLimit(rateLimit(cfg))
+	}
+	handlers.SetFeatures(features(cfg))
+
+	if sections := config.RestartRequired(old, cfg); len(sections) > 0 {
+		logrus.Warnf("config changes to %s take effect after a restart", strings.Join(sections, ", "))
+	}
+
+	logrus.Print("config reloaded")
+}
+
+func applyLogLevel(cfg *config.Config) {
+	level, err := logrus.ParseLevel(cfg.Log.Level)
+	if err != nil {
+		return
+	}
+
+	logrus.SetLevel(level)
+}
+
+func rateLimit(cfg *config.Config) handler.RateLimit {
+	return handler.RateLimit{
+		RequestsPerSecond: cfg.RateLimit.RequestsPerSecond,
+		Burst:             cfg.RateLimit.Burst,
 	}
 }
 
-func initConfig() error {
-	viper.AddConfigPath("configs")
-	viper.SetConfigName("config")
-	return viper.ReadInConfig()
+func features(cfg *config.Config) handler.Features {
+	return handler.Features{SignUp: cfg.Features.SignUp}
 }
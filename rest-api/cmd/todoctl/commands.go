@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/client"
+	"golang.org/x/term"
+)
+
+type app struct {
+	cfg        config
+	configPath string
+	out        *printer
+}
+
+func (a *app) client() (*client.Client, error) {
+	if a.cfg.Token == "" {
+		return nil, errors.New("not logged in, run todoctl login")
+	}
+
+	c := client.New(a.cfg.Server, client.WithToken(a.cfg.Token))
+	if a.cfg.Workspace != 0 {
+		c = c.WithWorkspace(a.cfg.Workspace)
+	}
+
+	return c, nil
+}
+
+func (a *app) login(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("login", flag.ContinueOnError)
+	username := flags.String("username", "", "username")
+	passwordStdin := flags.Bool("password-stdin", false, "read the password from stdin")
+	apiKey := flags.String("api-key", "", "store an api key instead of signing in")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	if *apiKey != "" {
+		a.cfg.Token = *apiKey
+		return a.save("Api key stored.")
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+
+	if *username == "" {
+		*username = prompt(reader, "Username: ")
+	}
+
+	var password string
+	if *passwordStdin {
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		password = strings.TrimRight(string(data), "\r\n")
+	} else {
+		var err error
+		password, err = promptPassword(reader, "Password: ")
+		if err != nil {
+			return err
+		}
+	}
+
+	c := client.New(a.cfg.Server)
+	result, err := c.SignIn(ctx, *username, password)
+	if err != nil {
+		return err
+	}
+
+	token := result.Token
+	if result.TwoFactorRequired {
+		code := prompt(reader, "Two-factor code: ")
+		token, err = c.SignInTwoFactor(ctx, result.ChallengeToken, code)
+		if err != nil {
+			return err
+		}
+	}
+
+	a.cfg.Token = token
+	return a.save("Logged in.")
+}
+
+func (a *app) logout() error {
+	a.cfg.Token = ""
+	return a.save("Logged out.")
+}
+
+func (a *app) save(message string) error {
+	if err := saveConfig(a.configPath, a.cfg); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, message)
+	return nil
+}
+
+func (a *app) lists(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "ls":
+		lists, err := c.GetLists(ctx)
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]string, 0, len(lists))
+		for _, list := range lists {
+			rows = append(rows, []string{strconv.Itoa(list.Id), list.Title, optional(list.Description)})
+		}
+		return a.out.print(lists, []string{"ID", "TITLE", "DESCRIPTION"}, rows)
+	case "create":
+		flags := flag.NewFlagSet("lists create", flag.ContinueOnError)
+		description := flags.String("description", "", "list description")
+		if err := flags.Parse(args[1:]); err != nil || flags.NArg() != 1 {
+			return errUsage
+		}
+
+		id, err := c.CreateList(ctx, todo.TodoList{Title: flags.Arg(0), Description: nonEmpty(*description)})
+		if err != nil {
+			return err
+		}
+		return a.out.status(map[string]int{"id": id}, fmt.Sprintf("Created list %d.", id))
+	case "rm":
+		id, err := idArg(args[1:])
+		if err != nil {
+			return err
+		}
+
+		if err := c.DeleteList(ctx, id); err != nil {
+			return err
+		}
+		return a.out.status(map[string]int{"id": id}, fmt.Sprintf("Deleted list %d.", id))
+	default:
+		return errUsage
+	}
+}
+
+func (a *app) items(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "ls":
+		listId, err := idArg(args[1:])
+		if err != nil {
+			return err
+		}
+
+		items, err := c.GetItems(ctx, listId)
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]string, 0, len(items))
+		for _, item := range items {
+			done := " "
+			if item.Done {
+				done = "x"
+			}
+			rows = append(rows, []string{strconv.Itoa(item.Id), "[" + done + "]", item.Title, optional(item.Description)})
+		}
+		return a.out.print(items, []string{"ID", "DONE", "TITLE", "DESCRIPTION"}, rows)
+	case "add":
+		flags := flag.NewFlagSet("items add", flag.ContinueOnError)
+		description := flags.String("description", "", "item description")
+		if err := flags.Parse(args[1:]); err != nil || flags.NArg() != 2 {
+			return errUsage
+		}
+
+		listId, err := strconv.Atoi(flags.Arg(0))
+		if err != nil {
+			return fmt.Errorf("invalid list id %q", flags.Arg(0))
+		}
+
+		id, err := c.CreateItem(ctx, listId, todo.TodoItem{Title: flags.Arg(1), Description: nonEmpty(*description)})
+		if err != nil {
+			return err
+		}
+		return a.out.status(map[string]int{"id": id}, fmt.Sprintf("Added item %d.", id))
+	case "done", "undone":
+		id, err := idArg(args[1:])
+		if err != nil {
+			return err
+		}
+
+		done := args[0] == "done"
+		if err := c.UpdateItem(ctx, id, todo.UpdateItemInput{Done: &done}); err != nil {
+			return err
+		}
+		return a.out.status(map[string]interface{}{"id": id, "done": done}, fmt.Sprintf("Marked item %d %s.", id, args[0]))
+	case "rm":
+		id, err := idArg(args[1:])
+		if err != nil {
+			return err
+		}
+
+		if err := c.DeleteItem(ctx, id); err != nil {
+			return err
+		}
+		return a.out.status(map[string]int{"id": id}, fmt.Sprintf("Deleted item %d.", id))
+	default:
+		return errUsage
+	}
+}
+
+func idArg(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, errUsage
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid id %q", args[0])
+	}
+
+	return id, nil
+}
+
+func optional(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
+
+func nonEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
+
+func prompt(reader *bufio.Reader, label string) string {
+	fmt.Fprint(os.Stderr, label)
+	line, _ := reader.ReadString('\n')
+	return strings.TrimSpace(line)
+}
+
+// promptPassword reads a password without echoing it when stdin is a
+// terminal. Piped input is read through reader, which may already hold
+// buffered lines.
+func promptPassword(reader *bufio.Reader, label string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return prompt(reader, label), nil
+	}
+
+	fmt.Fprint(os.Stderr, label)
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+
+	return string(password), nil
+}
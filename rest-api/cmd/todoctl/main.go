@@ -0,0 +1,109 @@
+// todoctl manages todo lists and items from the terminal.
+//
+//	todoctl login --server http://localhost:8008 --username alice
+//	todoctl lists ls
+//	todoctl items add 3 "Buy milk"
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+)
+
+const usage = `Usage: todoctl [global flags] <command> [args]
+
+Commands:
+  login                       sign in and store the token
+  logout                      forget the stored token
+  lists ls                    list lists
+  lists create <title>        create a list (--description)
+  lists rm <list-id>          delete a list
+  items ls <list-id>          list items of a list
+  items add <list-id> <title> add an item (--description)
+  items done <item-id>        mark an item done
+  items undone <item-id>      mark an item not done
+  items rm <item-id>          delete an item
+
+Global flags:
+`
+
+type globalFlags struct {
+	configPath string
+	server     string
+	output     string
+	workspace  int
+}
+
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	var global globalFlags
+
+	flags := flag.NewFlagSet("todoctl", flag.ContinueOnError)
+	flags.StringVar(&global.configPath, "config", defaultConfigPath(), "path of the config file")
+	flags.StringVar(&global.server, "server", "", "API base URL, overrides the stored one")
+	flags.StringVar(&global.output, "o", "table", "output format: table or json")
+	flags.IntVar(&global.workspace, "workspace", 0, "workspace id, defaults to the stored one")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		os.Exit(2)
+	}
+
+	if global.output != "table" && global.output != "json" {
+		fmt.Fprintln(os.Stderr, "todoctl: output must be table or json")
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, global, flags.Args()); err != nil {
+		if errors.Is(err, errUsage) {
+			flags.Usage()
+			os.Exit(2)
+		}
+		fmt.Fprintf(os.Stderr, "todoctl: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, global globalFlags, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	cfg, err := loadConfig(global.configPath)
+	if err != nil {
+		return err
+	}
+
+	if global.server != "" {
+		cfg.Server = global.server
+	}
+	if global.workspace != 0 {
+		cfg.Workspace = global.workspace
+	}
+
+	app := &app{cfg: cfg, configPath: global.configPath, out: newPrinter(os.Stdout, global.output)}
+
+	switch args[0] {
+	case "login":
+		return app.login(ctx, args[1:])
+	case "logout":
+		return app.logout()
+	case "lists":
+		return app.lists(ctx, args[1:])
+	case "items":
+		return app.items(ctx, args[1:])
+	default:
+		return errUsage
+	}
+}
@@ -0,0 +1,53 @@
+package todo
+
+import "time"
+
+const (
+	HealthStatusOK          = "ok"
+	HealthStatusUnavailable = "unavailable"
+)
+
+// HealthCheck is the outcome of checking one dependency for readiness.
+type HealthCheck struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Error     string  `json:"error,omitempty"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+// Readiness reports whether the instance should receive traffic. Status is
+// ok only when every check passed.
+type Readiness struct {
+	Status string        `json:"status"`
+	Checks []HealthCheck `json:"checks"`
+}
+
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuiltAt   string `json:"built_at"`
+	GoVersion string `json:"go_version"`
+}
+
+// DBPoolStats mirrors sql.DBStats with durations in milliseconds.
+type DBPoolStats struct {
+	MaxOpenConnections int     `json:"max_open_connections"`
+	OpenConnections    int     `json:"open_connections"`
+	InUse              int     `json:"in_use"`
+	Idle               int     `json:"idle"`
+	WaitCount          int64   `json:"wait_count"`
+	WaitDurationMs     float64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64   `json:"max_idle_closed"`
+	MaxLifetimeClosed  int64   `json:"max_lifetime_closed"`
+}
+
+// ServiceStatus is the detailed state of the instance for operators.
+type ServiceStatus struct {
+	Build                 BuildInfo   `json:"build"`
+	StartedAt             time.Time   `json:"started_at"`
+	UptimeSeconds         float64     `json:"uptime_seconds"`
+	ShuttingDown          bool        `json:"shutting_down"`
+	ExpectedSchemaVersion int         `json:"expected_schema_version"`
+	DBPool                DBPoolStats `json:"db_pool"`
+	Readiness             Readiness   `json:"readiness"`
+}
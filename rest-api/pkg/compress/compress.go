@@ -0,0 +1,234 @@
+// Package compress is gin middleware that compresses responses with
+// brotli or gzip, whichever the client prefers in Accept-Encoding.
+package compress
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultMinLength is the smallest body worth compressing; below it the
+// encoding overhead outweighs the savings.
+const DefaultMinLength = 1024
+
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+)
+
+// supported lists the encodings in order of preference when the client
+// weighs them equally.
+var supported = []string{encodingBrotli, encodingGzip}
+
+// compressible lists the content types worth compressing; images and
+// archives are already compressed.
+var compressible = []string{
+	"text/",
+	"application/json",
+	"application/problem+json",
+	"application/merge-patch+json",
+	"application/javascript",
+	"application/xml",
+	"application/msgpack",
+	"image/svg+xml",
+}
+
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+var pools = map[string]*sync.Pool{
+	encodingBrotli: {New: func() interface{} { return brotli.NewWriter(nil) }},
+	encodingGzip:   {New: func() interface{} { return gzip.NewWriter(nil) }},
+}
+
+// Middleware compresses responses of at least minLength bytes. Smaller
+// responses, responses that already carry a Content-Encoding and content
+// types that do not compress well are sent as is.
+func Middleware(minLength int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiate(c.GetHeader("Accept-Encoding"))
+		if encoding == "" || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		w := &writer{ResponseWriter: c.Writer, encoding: encoding, minLength: minLength}
+		c.Writer = w
+		defer w.close()
+
+		c.Next()
+	}
+}
+
+// negotiate returns the preferred supported encoding, or "" for identity.
+func negotiate(acceptEncoding string) string {
+	weights := make(map[string]float64)
+	wildcard := -1.0
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+		if coding == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if parsed, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+
+		if coding == "*" {
+			wildcard = q
+			continue
+		}
+		weights[coding] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, coding := range supported {
+		q, ok := weights[coding]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+
+	return best
+}
+
+// writer holds back the first minLength bytes of the body to decide
+// whether compressing is worth it, then streams through the encoder.
+type writer struct {
+	gin.ResponseWriter
+	encoding  string
+	minLength int
+
+	buf     []byte
+	decided bool
+	enc     encoder
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if w.decided {
+		if w.enc != nil {
+			return w.enc.Write(p)
+		}
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+	if len(w.buf) < w.minLength {
+		return len(p), nil
+	}
+
+	if err := w.decide(true); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func (w *writer) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *writer) Written() bool {
+	return len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+// Flush commits to the current decision so streamed responses are not
+// held back.
+func (w *writer) Flush() {
+	if !w.decided {
+		w.decide(len(w.buf) >= w.minLength)
+	}
+
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+
+	w.ResponseWriter.Flush()
+}
+
+// decide sends the buffered bytes, compressed when compress is set and
+// the response qualifies.
+func (w *writer) decide(compress bool) error {
+	w.decided = true
+
+	if compress && w.qualifies() {
+		header := w.Header()
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+
+		w.enc = pools[w.encoding].Get().(encoder)
+		w.enc.Reset(w.ResponseWriter)
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+
+	return err
+}
+
+func (w *writer) qualifies() bool {
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	status := w.Status()
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+
+	contentType := strings.ToLower(header.Get("Content-Type"))
+	for _, prefix := range compressible {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w *writer) close() {
+	if !w.decided {
+		w.decide(false)
+	}
+
+	if w.enc != nil {
+		w.enc.Close()
+		w.enc.Reset(nil)
+		pools[w.encoding].Put(w.enc)
+		w.enc = nil
+	}
+}
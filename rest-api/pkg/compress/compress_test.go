@@ -0,0 +1,141 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", encodingGzip},
+		{"br", encodingBrotli},
+		{"gzip, br", encodingBrotli},
+		{"GZIP", encodingGzip},
+		{"br;q=0.5, gzip", encodingGzip},
+		{"br;q=0, gzip;q=0.1", encodingGzip},
+		{"*", encodingBrotli},
+		{"*;q=0.5, gzip", encodingGzip},
+		{"*, br;q=0", encodingGzip},
+		{"gzip;q=0, br;q=0", ""},
+		{"deflate", ""},
+	}
+
+	for _, test := range tests {
+		if got := negotiate(test.acceptEncoding); got != test.want {
+			t.Errorf("negotiate(%q) = %q, want %q", test.acceptEncoding, got, test.want)
+		}
+	}
+}
+
+// serve answers with body of the given content type through the middleware.
+func serve(contentType, body, acceptEncoding string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Use(Middleware(16))
+	router.GET("/", func(c *gin.Context) {
+		c.Data(http.StatusOK, contentType, []byte(body))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func decode(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+
+	var data []byte
+	var err error
+	switch encoding {
+	case encodingGzip:
+		var r *gzip.Reader
+		if r, err = gzip.NewReader(bytes.NewReader(body)); err == nil {
+			data, err = ioutil.ReadAll(r)
+		}
+	case encodingBrotli:
+		data, err = ioutil.ReadAll(brotli.NewReader(bytes.NewReader(body)))
+	default:
+		data = body
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestMiddleware(t *testing.T) {
+	long := strings.Repeat(`{"title":"compress me"}`, 10)
+
+	tests := []struct {
+		name           string
+		contentType    string
+		body           string
+		acceptEncoding string
+		encoding       string
+	}{
+		{"gzip", "application/json", long, "gzip", encodingGzip},
+		{"brotli preferred", "application/json", long, "gzip, br", encodingBrotli},
+		{"weights", "text/csv", long, "br;q=0.1, gzip;q=0.9", encodingGzip},
+		{"no accept-encoding", "application/json", long, "", ""},
+		{"below min length", "application/json", `{}`, "gzip", ""},
+		{"already compressed type", "image/png", long, "gzip", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serve(test.contentType, test.body, test.acceptEncoding)
+
+			if got := w.Header().Get("Content-Encoding"); got != test.encoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, test.encoding)
+			}
+			if vary := w.Header().Values("Vary"); len(vary) != 1 || vary[0] != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", vary)
+			}
+			if got := decode(t, test.encoding, w.Body.Bytes()); got != test.body {
+				t.Errorf("body = %q, want %q", got, test.body)
+			}
+		})
+	}
+}
+
+func TestMiddlewareKeepsContentEncoding(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(1))
+	router.GET("/", func(c *gin.Context) {
+		c.Header("Content-Encoding", "gzip")
+		c.Data(http.StatusOK, "application/json", []byte("already gzipped body"))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "br")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Errorf("Content-Encoding = %q, want gzip", got)
+	}
+	if got := w.Body.String(); got != "already gzipped body" {
+		t.Errorf("body = %q", got)
+	}
+}
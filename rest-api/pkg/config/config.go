@@ -0,0 +1,435 @@
+// Package config loads the settings of the application into a typed Config.
+//
+// Sources, from lowest to highest precedence:
+//
+//  1. the defaults in this package
+//  2. the config file, configs/config.yml unless TODO_CONFIG_FILE is set
+//  3. a .env file in the working directory; it only sets variables that are
+//     not already in the environment
+//  4. environment variables named TODO_ followed by the key with dots
+//     replaced by underscores, e.g. TODO_DB_HOST or TODO_SERVER_TLS_CERT_FILE
+//  5. secret files: db.password_file, oidc.client_secret_file and
+//     auth.password_salt_file take precedence over db.password,
+//     oidc.client_secret and auth.password_salt
+//
+// DB_PASSWORD and OIDC_CLIENT_SECRET are still accepted for the two older
+// secrets. The list of signing keys can only be set in the config file.
+//
+// The log, rate_limit and features sections apply while the server runs;
+// changes to the others take effect after a restart.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/spf13/viper"
+)
+
+const (
+	DefaultFile = "configs/config.yml"
+	envPrefix   = "TODO"
+	dateLayout  = "2006-01-02"
+
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+)
+
+type Config struct {
+	// Env is development or production. Development allows settings that
+	// are only safe on a workstation, such as running without a password
+	// salt.
+	Env       string    `mapstructure:"env"`
+	Port      string    `mapstructure:"port"`
+	GRPCPort  string    `mapstructure:"grpc_port"`
+	Log       Log       `mapstructure:"log"`
+	Server    Server    `mapstructure:"server"`
+	Metrics   Metrics   `mapstructure:"metrics"`
+	Tracing   Tracing   `mapstructure:"tracing"`
+	API       API       `mapstructure:"api"`
+	RateLimit RateLimit `mapstructure:"rate_limit"`
+	Features  Features  `mapstructure:"features"`
+	DB        DB        `mapstructure:"db"`
+	Auth      Auth      `mapstructure:"auth"`
+	OIDC      OIDC      `mapstructure:"oidc"`
+}
+
+func (c *Config) Development() bool {
+	return c.Env == EnvDevelopment
+}
+
+type Log struct {
+	// Level is a logrus level: panic, fatal, error, warn, info, debug or trace.
+	Level string `mapstructure:"level"`
+}
+
+type Server struct {
+	ReadTimeout       time.Duration `mapstructure:"read_timeout"`
+	ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout"`
+	WriteTimeout      time.Duration `mapstructure:"write_timeout"`
+	IdleTimeout       time.Duration `mapstructure:"idle_timeout"`
+	DrainPeriod       time.Duration `mapstructure:"drain_period"`
+	ShutdownTimeout   time.Duration `mapstructure:"shutdown_timeout"`
+	TLS               TLS           `mapstructure:"tls"`
+}
+
+type TLS struct {
+	CertFile       string        `mapstructure:"cert_file"`
+	KeyFile        string        `mapstructure:"key_file"`
+	MinVersion     string        `mapstructure:"min_version"`
+	CipherPolicy   string        `mapstructure:"cipher_policy"`
+	ClientCAFile   string        `mapstructure:"client_ca_file"`
+	ClientAuth     string        `mapstructure:"client_auth"`
+	ReloadInterval time.Duration `mapstructure:"reload_interval"`
+}
+
+type Metrics struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Port    string `mapstructure:"port"`
+}
+
+type Tracing struct {
+	Exporter    string  `mapstructure:"exporter"`
+	Endpoint    string  `mapstructure:"endpoint"`
+	Insecure    bool    `mapstructure:"insecure"`
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+}
+
+// API announces the retirement of v1. The dates, formatted as 2006-01-02,
+// are sent in the Deprecation and Sunset headers of v1 responses; a header
+// is left out while its date is empty.
+type API struct {
+	V1DeprecatedAt string `mapstructure:"v1_deprecated_at"`
+	V1Sunset       string `mapstructure:"v1_sunset"`
+}
+
+// V1Deprecation returns the parsed dates, zero for those that are not set.
+func (a API) V1Deprecation() (deprecatedAt, sunset time.Time) {
+	deprecatedAt, _ = parseDate(a.V1DeprecatedAt)
+	sunset, _ = parseDate(a.V1Sunset)
+	return deprecatedAt, sunset
+}
+
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(dateLayout, value)
+}
+
+// RateLimit bounds the requests per second of each client address on the
+// api, auth, admin and graphql routes. Zero requests_per_second turns it off.
+type RateLimit struct {
+	RequestsPerSecond float64 `mapstructure:"requests_per_second"`
+	Burst             int     `mapstructure:"burst"`
+}
+
+type Features struct {
+	SignUp bool `mapstructure:"sign_up"`
+}
+
+type DB struct {
+	Host         string `mapstructure:"host"`
+	Port         string `mapstructure:"port"`
+	Username     string `mapstructure:"username"`
+	Password     string `mapstructure:"password"`
+	PasswordFile string `mapstructure:"password_file"`
+	DBName       string `mapstructure:"dbname"`
+	SSLMode      string `mapstructure:"sslmode"`
+
+	MaxOpenConns    int           `mapstructure:"max_open_conns"`
+	MaxIdleConns    int           `mapstructure:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time"`
+	ConnectTimeout  time.Duration `mapstructure:"connect_timeout"`
+
+	// Replica serves list and item reads when Host is set. It uses the
+	// credentials and pool settings of the primary.
+	Replica DBReplica `mapstructure:"replica"`
+}
+
+type DBReplica struct {
+	Host string `mapstructure:"host"`
+	Port string `mapstructure:"port"`
+}
+
+type Auth struct {
+	SigningKeyId string        `mapstructure:"signing_key_id"`
+	Keys         []Key         `mapstructure:"keys"`
+	TokenTTL     time.Duration `mapstructure:"token_ttl"`
+	// PasswordSalt is mixed into password hashes. Changing it invalidates
+	// every stored password. It is required outside development.
+	PasswordSalt     string `mapstructure:"password_salt"`
+	PasswordSaltFile string `mapstructure:"password_salt_file"`
+}
+
+// Key is a token signing key. Keys that are only kept to verify tokens
+// signed before a rotation need just the public key.
+type Key struct {
+	Id             string `mapstructure:"id"`
+	Algorithm      string `mapstructure:"algorithm"`
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	PrivateKeyEnv  string `mapstructure:"private_key_env"`
+	PublicKeyFile  string `mapstructure:"public_key_file"`
+	PublicKeyEnv   string `mapstructure:"public_key_env"`
+}
+
+type OIDC struct {
+	Enabled          bool     `mapstructure:"enabled"`
+	Issuer           string   `mapstructure:"issuer"`
+	ClientId         string   `mapstructure:"client_id"`
+	ClientSecret     string   `mapstructure:"client_secret"`
+	ClientSecretFile string   `mapstructure:"client_secret_file"`
+	RedirectURL      string   `mapstructure:"redirect_url"`
+	Scopes           []string `mapstructure:"scopes"`
+}
+
+// defaults lists every key, which also makes each of them settable from
+// the environment.
+var defaults = map[string]interface{}{
+	"env":       EnvProduction,
+	"port":      "8008",
+	"grpc_port": "9090",
+
+	"log.level": "info",
+
+	"server.read_timeout":        10 * time.Second,
+	"server.read_header_timeout": 5 * time.Second,
+	"server.write_timeout":       10 * time.Second,
+	"server.idle_timeout":        60 * time.Second,
+	"server.drain_period":        5 * time.Second,
+	"server.shutdown_timeout":    20 * time.Second,
+
+	"server.tls.cert_file":       "",
+	"server.tls.key_file":        "",
+	"server.tls.min_version":     "1.2",
+	"server.tls.cipher_policy":   "intermediate",
+	"server.tls.client_ca_file":  "",
+	"server.tls.client_auth":     "optional",
+	"server.tls.reload_interval": 30 * time.Second,
+
+	"metrics.enabled": true,
+	"metrics.port":    "",
+
+	"tracing.exporter":     "none",
+	"tracing.endpoint":     "",
+	"tracing.insecure":     false,
+	"tracing.sample_ratio": 1.0,
+
+	"api.v1_deprecated_at": "",
+	"api.v1_sunset":        "",
+
+	"rate_limit.requests_per_second": 0.0,
+	"rate_limit.burst":               0,
+
+	"features.sign_up": true,
+
+	"db.host":          "localhost",
+	"db.port":          "5432",
+	"db.username":      "postgres",
+	"db.password":      "",
+	"db.password_file": "",
+	"db.dbname":        "postgres",
+	"db.sslmode":       "disable",
+
+	"db.max_open_conns":     20,
+	"db.max_idle_conns":     10,
+	"db.conn_max_lifetime":  30 * time.Minute,
+	"db.conn_max_idle_time": 5 * time.Minute,
+	"db.connect_timeout":    30 * time.Second,
+	"db.replica.host":       "",
+	"db.replica.port":       "5432",
+
+	"auth.signing_key_id":     "",
+	"auth.token_ttl":          12 * time.Hour,
+	"auth.password_salt":      "",
+	"auth.password_salt_file": "",
+
+	"oidc.enabled":            false,
+	"oidc.issuer":             "",
+	"oidc.client_id":          "",
+	"oidc.client_secret":      "",
+	"oidc.client_secret_file": "",
+	"oidc.redirect_url":       "",
+	"oidc.scopes":             []string{"openid", "profile", "email"},
+}
+
+// legacyEnv are variable names used before the TODO_ prefix existed.
+var legacyEnv = map[string]string{
+	"db.password":        "DB_PASSWORD",
+	"oidc.client_secret": "OIDC_CLIENT_SECRET",
+}
+
+// Loader reads the configuration and reads it again on Reload.
+type Loader struct {
+	v    *viper.Viper
+	file string
+
+	mu      sync.Mutex
+	current *Config
+	version fileVersion
+}
+
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewLoader reads from file, or from TODO_CONFIG_FILE or DefaultFile when
+// file is empty.
+func NewLoader(file string) *Loader {
+	if file == "" {
+		file = os.Getenv(envPrefix + "_CONFIG_FILE")
+	}
+	if file == "" {
+		file = DefaultFile
+	}
+
+	v := viper.New()
+	v.SetConfigFile(file)
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	for key, value := range defaults {
+		v.SetDefault(key, value)
+	}
+	for key, name := range legacyEnv {
+		v.BindEnv(key, envName(key), name)
+	}
+
+	return &Loader{v: v, file: file}
+}
+
+// Load reads .env and the config file and validates the result.
+func (l *Loader) Load() (*Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("error loading .env: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cfg, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+
+	l.current = cfg
+	return cfg, nil
+}
+
+// Reload reads the config file again. An invalid configuration is rejected
+// and the previous one stays current. Variables from .env are not read again.
+func (l *Loader) Reload() (old, cfg *Config, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	old = l.current
+	cfg, err = l.read()
+	if err != nil {
+		return old, nil, err
+	}
+
+	l.current = cfg
+	return old, cfg, nil
+}
+
+func (l *Loader) Current() *Config {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.current
+}
+
+// Watch reloads the configuration whenever the file changes, until stop is
+// closed, and passes the outcome to onReload.
+func (l *Loader) Watch(stop <-chan struct{}, interval time.Duration, onReload func(old, cfg *Config, err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if l.changed() {
+				onReload(l.Reload())
+			}
+		}
+	}
+}
+
+func (l *Loader) changed() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stat() != l.version
+}
+
+func (l *Loader) stat() fileVersion {
+	info, err := os.Stat(l.file)
+	if err != nil {
+		return fileVersion{}
+	}
+
+	return fileVersion{modTime: info.ModTime(), size: info.Size()}
+}
+
+// read must be called with mu held.
+func (l *Loader) read() (*Config, error) {
+	version := l.stat()
+	if err := l.v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", l.file, err)
+	}
+	l.version = version
+
+	var cfg Config
+	if err := l.v.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", l.file, err)
+	}
+
+	problems := cfg.readSecrets()
+	problems = append(problems, cfg.validate()...)
+	if len(problems) > 0 {
+		return nil, problems
+	}
+
+	return &cfg, nil
+}
+
+// readSecrets replaces secrets with the contents of their files.
+func (c *Config) readSecrets() Problems {
+	var problems Problems
+	for _, secret := range []struct {
+		key   string
+		file  string
+		value *string
+	}{
+		{"db.password_file", c.DB.PasswordFile, &c.DB.Password},
+		{"oidc.client_secret_file", c.OIDC.ClientSecretFile, &c.OIDC.ClientSecret},
+		{"auth.password_salt_file", c.Auth.PasswordSaltFile, &c.Auth.PasswordSalt},
+	} {
+		if secret.file == "" {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(secret.file)
+		if err != nil {
+			problems.add(secret.key, err.Error())
+			continue
+		}
+
+		*secret.value = strings.TrimRight(string(data), "\r\n")
+	}
+
+	return problems
+}
+
+func envName(key string) string {
+	return envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
@@ -0,0 +1,233 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const validConfig = `
+env: "production"
+port: "8008"
+log:
+  level: "info"
+auth:
+  password_salt: "from-file"
+  signing_key_id: "a"
+  keys:
+    - id: "a"
+      algorithm: "EdDSA"
+`
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	file := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return file
+}
+
+func setenv(t *testing.T, key, value string) {
+	t.Helper()
+
+	old, ok := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, "config.yml", validConfig+`
+db:
+  host: "file-host"
+  password: "file-password"
+`)
+
+	cfg, err := NewLoader(file).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DB.Host != "file-host" || cfg.DB.Password != "file-password" {
+		t.Errorf("file: host %q, password %q", cfg.DB.Host, cfg.DB.Password)
+	}
+	if cfg.DB.Port != "5432" {
+		t.Errorf("default port = %q, want 5432", cfg.DB.Port)
+	}
+
+	setenv(t, "TODO_DB_HOST", "env-host")
+	setenv(t, "TODO_DB_PASSWORD", "env-password")
+
+	cfg, err = NewLoader(file).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DB.Host != "env-host" || cfg.DB.Password != "env-password" {
+		t.Errorf("env: host %q, password %q", cfg.DB.Host, cfg.DB.Password)
+	}
+
+	secret := writeFile(t, dir, "db-password", "secret-password\n")
+	setenv(t, "TODO_DB_PASSWORD_FILE", secret)
+
+	cfg, err = NewLoader(file).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DB.Password != "secret-password" {
+		t.Errorf("secret file: password %q, want secret-password", cfg.DB.Password)
+	}
+}
+
+func TestLoadLegacyEnv(t *testing.T) {
+	file := writeFile(t, t.TempDir(), "config.yml", validConfig)
+	setenv(t, "DB_PASSWORD", "legacy")
+
+	cfg, err := NewLoader(file).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DB.Password != "legacy" {
+		t.Errorf("password = %q, want legacy", cfg.DB.Password)
+	}
+}
+
+func TestValidateProblems(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		problem string
+	}{
+		{"unknown env", `env: "staging"`, `env: "staging" is not one of development, production`},
+		{"log level", `log: {level: "loud"}`, `log.level: unknown level "loud"`},
+		{"port", `port: "http"`, `port: "http" is not a port number`},
+		{"shared port", `grpc_port: "8008"`, `grpc_port: 8008 is already used by port`},
+		{"negative timeout", `server: {read_timeout: -1s}`, `server.read_timeout: must not be negative`},
+		{"client auth", `server: {tls: {client_auth: "maybe"}}`, `server.tls.client_auth: "maybe" is not one of`},
+		{"sunset date", `api: {v1_sunset: "soon"}`, `api.v1_sunset: "soon" is not a date like 2006-01-02`},
+		{"rate limit", `rate_limit: {requests_per_second: -1}`, `rate_limit.requests_per_second: must not be negative`},
+		{"idle conns", `db: {max_open_conns: 5, max_idle_conns: 10}`, `db.max_idle_conns: must not exceed db.max_open_conns`},
+		{"signing key", `auth: {password_salt: "s", signing_key_id: "b", keys: [{id: "a", algorithm: "RS256"}]}`, `auth.signing_key_id: "b" is not one of auth.keys`},
+		{"salt outside development", `auth: {password_salt: ""}`, `auth.password_salt: is required outside development`},
+		{"keys outside development", `auth: {password_salt: "s", keys: []}`, `auth.keys: are required outside development`},
+		{"oidc", `oidc: {enabled: true, issuer: ""}`, `oidc.issuer: is required`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file := writeFile(t, t.TempDir(), "config.yml", validConfig+test.config)
+
+			_, err := NewLoader(file).Load()
+
+			var problems Problems
+			if !errors.As(err, &problems) {
+				t.Fatalf("err = %v, want Problems", err)
+			}
+			if !strings.Contains(problems.Error(), test.problem) {
+				t.Errorf("problems %q do not mention %q", problems.Error(), test.problem)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	file := writeFile(t, t.TempDir(), "config.yml", validConfig+`
+port: "0"
+tracing: {sample_ratio: 2}
+`)
+
+	_, err := NewLoader(file).Load()
+
+	var problems Problems
+	if !errors.As(err, &problems) {
+		t.Fatalf("err = %v, want Problems", err)
+	}
+	if len(problems) != 2 {
+		t.Errorf("problems = %q, want 2", problems)
+	}
+}
+
+func TestDevelopmentAllowsMissingSaltAndKeys(t *testing.T) {
+	file := writeFile(t, t.TempDir(), "config.yml", validConfig+`
+env: "development"
+auth: {password_salt: "", keys: []}
+`)
+
+	if _, err := NewLoader(file).Load(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	file := writeFile(t, t.TempDir(), "config.yml", validConfig)
+
+	loader := NewLoader(file)
+	if _, err := loader.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, filepath.Dir(file), "config.yml", validConfig+`
+log: {level: "debug"}
+rate_limit: {requests_per_second: 5, burst: 10}
+features: {sign_up: false}
+`)
+
+	old, cfg, err := loader.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Log.Level != "debug" || cfg.RateLimit.RequestsPerSecond != 5 || cfg.Features.SignUp {
+		t.Errorf("reloaded %+v", cfg)
+	}
+	if sections := RestartRequired(old, cfg); len(sections) != 0 {
+		t.Errorf("RestartRequired = %v, want none", sections)
+	}
+	if loader.Current() != cfg {
+		t.Error("reloaded config is not current")
+	}
+
+	writeFile(t, filepath.Dir(file), "config.yml", validConfig+`
+port: "8080"
+db: {host: "elsewhere"}
+`)
+
+	old, cfg, err = loader.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sections := RestartRequired(old, cfg); !reflect.DeepEqual(sections, []string{"port", "db"}) {
+		t.Errorf("RestartRequired = %v, want [port db]", sections)
+	}
+}
+
+func TestReloadKeepsCurrentWhenInvalid(t *testing.T) {
+	file := writeFile(t, t.TempDir(), "config.yml", validConfig)
+
+	loader := NewLoader(file)
+	current, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, filepath.Dir(file), "config.yml", validConfig+`log: {level: "loud"}`)
+
+	if _, _, err := loader.Reload(); err == nil {
+		t.Fatal("invalid config was accepted")
+	}
+	if loader.Current() != current {
+		t.Error("invalid reload replaced the current config")
+	}
+}
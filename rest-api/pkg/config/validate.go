@@ -0,0 +1,219 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Problems lists everything wrong with a configuration, so that all of it
+// can be fixed in one go.
+type Problems []string
+
+func (p *Problems) add(key, problem string) {
+	*p = append(*p, key+": "+problem)
+}
+
+func (p Problems) Error() string {
+	return "invalid configuration:\n  " + strings.Join(p, "\n  ")
+}
+
+// reloadable are the sections that take effect without a restart.
+var reloadable = map[string]bool{
+	"log":        true,
+	"rate_limit": true,
+	"features":   true,
+}
+
+func (c *Config) validate() Problems {
+	var problems Problems
+
+	oneOf(&problems, "env", c.Env, EnvDevelopment, EnvProduction)
+
+	if _, err := logrus.ParseLevel(c.Log.Level); err != nil {
+		problems.add("log.level", fmt.Sprintf("unknown level %q", c.Log.Level))
+	}
+
+	ports := map[string]string{}
+	for _, port := range []struct{ key, value string }{
+		{"port", c.Port},
+		{"grpc_port", c.GRPCPort},
+		{"metrics.port", c.Metrics.Port},
+	} {
+		if port.value == "" && port.key == "metrics.port" {
+			continue
+		}
+		if n, err := strconv.Atoi(port.value); err != nil || n < 1 || n > 65535 {
+			problems.add(port.key, fmt.Sprintf("%q is not a port number", port.value))
+			continue
+		}
+		if other, ok := ports[port.value]; ok {
+			problems.add(port.key, fmt.Sprintf("%s is already used by %s", port.value, other))
+			continue
+		}
+		ports[port.value] = port.key
+	}
+
+	for _, timeout := range []struct {
+		key   string
+		value int64
+	}{
+		{"server.read_timeout", int64(c.Server.ReadTimeout)},
+		{"server.read_header_timeout", int64(c.Server.ReadHeaderTimeout)},
+		{"server.write_timeout", int64(c.Server.WriteTimeout)},
+		{"server.idle_timeout", int64(c.Server.IdleTimeout)},
+		{"server.drain_period", int64(c.Server.DrainPeriod)},
+		{"server.shutdown_timeout", int64(c.Server.ShutdownTimeout)},
+		{"server.tls.reload_interval", int64(c.Server.TLS.ReloadInterval)},
+	} {
+		if timeout.value < 0 {
+			problems.add(timeout.key, "must not be negative")
+		}
+	}
+
+	tls := c.Server.TLS
+	if tls.CertFile != "" || tls.KeyFile != "" {
+		if tls.CertFile == "" {
+			problems.add("server.tls.cert_file", "is required with a key file")
+		}
+		if tls.KeyFile == "" {
+			problems.add("server.tls.key_file", "is required with a certificate file")
+		}
+	}
+	oneOf(&problems, "server.tls.min_version", tls.MinVersion, "", "1.2", "1.3")
+	oneOf(&problems, "server.tls.cipher_policy", tls.CipherPolicy, "", "intermediate", "modern")
+	oneOf(&problems, "server.tls.client_auth", tls.ClientAuth, "", "optional", "require")
+	if tls.ClientCAFile != "" && tls.CertFile == "" {
+		problems.add("server.tls.client_ca_file", "requires server.tls.cert_file")
+	}
+
+	oneOf(&problems, "tracing.exporter", c.Tracing.Exporter, "", "none", "stdout", "otlp")
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems.add("tracing.sample_ratio", "must be between 0 and 1")
+	}
+
+	deprecatedAt, err := parseDate(c.API.V1DeprecatedAt)
+	if err != nil {
+		problems.add("api.v1_deprecated_at", fmt.Sprintf("%q is not a date like %s", c.API.V1DeprecatedAt, dateLayout))
+	}
+	sunset, err := parseDate(c.API.V1Sunset)
+	if err != nil {
+		problems.add("api.v1_sunset", fmt.Sprintf("%q is not a date like %s", c.API.V1Sunset, dateLayout))
+	}
+	if !deprecatedAt.IsZero() && !sunset.IsZero() && sunset.Before(deprecatedAt) {
+		problems.add("api.v1_sunset", "must not be before api.v1_deprecated_at")
+	}
+
+	if c.RateLimit.RequestsPerSecond < 0 {
+		problems.add("rate_limit.requests_per_second", "must not be negative")
+	}
+	if c.RateLimit.Burst < 0 {
+		problems.add("rate_limit.burst", "must not be negative")
+	}
+
+	required(&problems, "db.host", c.DB.Host)
+	required(&problems, "db.port", c.DB.Port)
+	required(&problems, "db.username", c.DB.Username)
+	required(&problems, "db.dbname", c.DB.DBName)
+	oneOf(&problems, "db.sslmode", c.DB.SSLMode,
+		"disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+
+	for _, limit := range []struct {
+		key   string
+		value int64
+	}{
+		{"db.max_open_conns", int64(c.DB.MaxOpenConns)},
+		{"db.max_idle_conns", int64(c.DB.MaxIdleConns)},
+		{"db.conn_max_lifetime", int64(c.DB.ConnMaxLifetime)},
+		{"db.conn_max_idle_time", int64(c.DB.ConnMaxIdleTime)},
+		{"db.connect_timeout", int64(c.DB.ConnectTimeout)},
+	} {
+		if limit.value < 0 {
+			problems.add(limit.key, "must not be negative")
+		}
+	}
+	if c.DB.MaxOpenConns > 0 && c.DB.MaxIdleConns > c.DB.MaxOpenConns {
+		problems.add("db.max_idle_conns", "must not exceed db.max_open_conns")
+	}
+	if c.DB.Replica.Host != "" {
+		required(&problems, "db.replica.port", c.DB.Replica.Port)
+	}
+
+	if c.Auth.PasswordSalt == "" && !c.Development() {
+		problems.add("auth.password_salt", "is required outside development; set it, auth.password_salt_file or TODO_AUTH_PASSWORD_SALT")
+	}
+	if c.Auth.TokenTTL <= 0 {
+		problems.add("auth.token_ttl", "must be positive")
+	}
+	if len(c.Auth.Keys) == 0 && !c.Development() {
+		problems.add("auth.keys", "are required outside development; an ephemeral key invalidates every token on restart and differs between replicas")
+	}
+	if len(c.Auth.Keys) > 0 {
+		ids := map[string]bool{}
+		for i, key := range c.Auth.Keys {
+			prefix := fmt.Sprintf("auth.keys[%d]", i)
+			if key.Id == "" {
+				problems.add(prefix+".id", "is required")
+			} else if ids[key.Id] {
+				problems.add(prefix+".id", fmt.Sprintf("%q is configured twice", key.Id))
+			}
+			ids[key.Id] = true
+			oneOf(&problems, prefix+".algorithm", key.Algorithm, "RS256", "EdDSA")
+		}
+		if !ids[c.Auth.SigningKeyId] {
+			problems.add("auth.signing_key_id", fmt.Sprintf("%q is not one of auth.keys", c.Auth.SigningKeyId))
+		}
+	}
+
+	if c.OIDC.Enabled {
+		required(&problems, "oidc.issuer", c.OIDC.Issuer)
+		required(&problems, "oidc.client_id", c.OIDC.ClientId)
+		required(&problems, "oidc.redirect_url", c.OIDC.RedirectURL)
+	}
+
+	return problems
+}
+
+func required(problems *Problems, key, value string) {
+	if value == "" {
+		problems.add(key, "is required")
+	}
+}
+
+func oneOf(problems *Problems, key, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+
+	var names []string
+	for _, a := range allowed {
+		if a != "" {
+			names = append(names, a)
+		}
+	}
+	problems.add(key, fmt.Sprintf("%q is not one of %s", value, strings.Join(names, ", ")))
+}
+
+// RestartRequired returns the sections that differ between old and cfg but
+// only take effect after a restart.
+func RestartRequired(old, cfg *Config) []string {
+	var sections []string
+
+	oldValue, newValue := reflect.ValueOf(*old), reflect.ValueOf(*cfg)
+	for i := 0; i < oldValue.NumField(); i++ {
+		name := oldValue.Type().Field(i).Tag.Get("mapstructure")
+		if reloadable[name] {
+			continue
+		}
+		if !reflect.DeepEqual(oldValue.Field(i).Interface(), newValue.Field(i).Interface()) {
+			sections = append(sections, name)
+		}
+	}
+
+	return sections
+}
@@ -0,0 +1,119 @@
+// Package graph serves the todo API over GraphQL. Resolvers call the same
+// services as the REST handlers, so authorization and workspace scoping
+// are identical.
+package graph
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"strings"
+
+	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/service"
+	"akhmet.com/rest-api/pkg/validate"
+	"github.com/graph-gophers/graphql-go"
+)
+
+//go:embed schema.graphql
+var schema string
+
+type viewerKey struct{}
+
+// Viewer is the caller a GraphQL request is resolved for.
+type Viewer struct {
+	UserId      int
+	WorkspaceId int
+	Scopes      todo.Scopes
+}
+
+type request struct {
+	viewer    Viewer
+	items     *itemLoader
+	members   *memberLoader
+	workspace *workspaceLoader
+}
+
+// WithViewer attaches the caller and a fresh set of loaders to ctx. Loaders
+// live for a single request so nothing is cached across users.
+func WithViewer(ctx context.Context, viewer Viewer) context.Context {
+	return context.WithValue(ctx, viewerKey{}, newRequest(viewer))
+}
+
+func newRequest(viewer Viewer) *request {
+	return &request{
+		viewer:    viewer,
+		items:     newItemLoader(),
+		members:   newMemberLoader(),
+		workspace: &workspaceLoader{},
+	}
+}
+
+func fromContext(ctx context.Context) *request {
+	req, _ := ctx.Value(viewerKey{}).(*request)
+	if req == nil {
+		return newRequest(Viewer{})
+	}
+
+	return req
+}
+
+// NewSchema parses the schema and binds it to the services.
+func NewSchema(services *service.Service) *graphql.Schema {
+	return graphql.MustParseSchema(schema, &Resolver{services: services})
+}
+
+// ScopeError is returned when the token lacks a scope a field needs. The
+// missing scopes are reported in the error extensions.
+type ScopeError struct {
+	Missing []string
+}
+
+func (e *ScopeError) Error() string {
+	return "missing scope: " + strings.Join(e.Missing, ", ")
+}
+
+func (e *ScopeError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":           "FORBIDDEN",
+		"missing_scopes": e.Missing,
+	}
+}
+
+// InputError is returned when mutation input fails validation. Every
+// failing field is reported in the error extensions.
+type InputError struct {
+	Errors validate.Errors
+}
+
+func (e *InputError) Error() string {
+	return e.Errors.Error()
+}
+
+func (e *InputError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":   "BAD_USER_INPUT",
+		"fields": e.Errors,
+	}
+}
+
+// inputError turns validation failures into an InputError and leaves
+// other errors alone.
+func inputError(err error) error {
+	var errs validate.Errors
+	if errors.As(err, &errs) {
+		return &InputError{Errors: errs}
+	}
+
+	return err
+}
+
+func requireScopes(ctx context.Context, scopes ...string) (Viewer, error) {
+	viewer := fromContext(ctx).viewer
+
+	if missing := viewer.Scopes.Missing(scopes...); len(missing) > 0 {
+		return viewer, &ScopeError{Missing: missing}
+	}
+
+	return viewer, nil
+}
@@ -0,0 +1,99 @@
+package graph
+
+import (
+	"sync"
+
+	"akhmet.com/rest-api"
+)
+
+// itemLoader batches item lookups per list. Lists resolved in the same
+// request are queued with prime, and the first load fetches items for all
+// queued lists in one query instead of one query per list.
+type itemLoader struct {
+	mu      sync.Mutex
+	pending []int
+	cache   map[int][]todo.TodoItem
+}
+
+func newItemLoader() *itemLoader {
+	return &itemLoader{cache: make(map[int][]todo.TodoItem)}
+}
+
+func (l *itemLoader) prime(listIds ...int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.pending = append(l.pending, listIds...)
+}
+
+func (l *itemLoader) load(listId int, fetch func(listIds []int) (map[int][]todo.TodoItem, error)) ([]todo.TodoItem, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if items, ok := l.cache[listId]; ok {
+		return items, nil
+	}
+
+	batch := []int{listId}
+	for _, id := range l.pending {
+		if _, ok := l.cache[id]; !ok && id != listId {
+			batch = append(batch, id)
+		}
+	}
+	l.pending = nil
+
+	items, err := fetch(batch)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range batch {
+		l.cache[id] = items[id]
+	}
+
+	return l.cache[listId], nil
+}
+
+// memberLoader memoizes workspace members, since every list in a request
+// belongs to the same workspace.
+type memberLoader struct {
+	mu    sync.Mutex
+	cache map[int][]todo.WorkspaceMember
+}
+
+func newMemberLoader() *memberLoader {
+	return &memberLoader{cache: make(map[int][]todo.WorkspaceMember)}
+}
+
+func (l *memberLoader) load(workspaceId int, fetch func() ([]todo.WorkspaceMember, error)) ([]todo.WorkspaceMember, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if members, ok := l.cache[workspaceId]; ok {
+		return members, nil
+	}
+
+	members, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	l.cache[workspaceId] = members
+
+	return members, nil
+}
+
+// workspaceLoader memoizes the request's workspace so that resolving it
+// for every list costs a single lookup.
+type workspaceLoader struct {
+	once      sync.Once
+	workspace todo.Workspace
+	err       error
+}
+
+func (l *workspaceLoader) load(fetch func() (todo.Workspace, error)) (todo.Workspace, error) {
+	l.once.Do(func() {
+		l.workspace, l.err = fetch()
+	})
+
+	return l.workspace, l.err
+}
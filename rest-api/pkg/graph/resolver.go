@@ -0,0 +1,323 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/service"
+	"akhmet.com/rest-api/pkg/validate"
+)
+
+// Resolver is the root of both Query and Mutation.
+type Resolver struct {
+	services *service.Service
+}
+
+type listInput struct {
+	Title       string
+	Description *string
+}
+
+func (i listInput) list() todo.TodoList {
+	return todo.TodoList{Title: i.Title, Description: i.Description}
+}
+
+func (i listInput) item() todo.TodoItem {
+	return todo.TodoItem{Title: i.Title, Description: i.Description}
+}
+
+func (r *Resolver) Me(ctx context.Context) (*userResolver, error) {
+	user, err := r.services.Authorization.GetUserById(ctx, fromContext(ctx).viewer.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &userResolver{user: user}, nil
+}
+
+func (r *Resolver) Workspace(ctx context.Context) (*workspaceResolver, error) {
+	viewer, err := requireScopes(ctx, todo.ScopeListsRead)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.workspace(ctx, viewer)
+}
+
+func (r *Resolver) workspace(ctx context.Context, viewer Viewer) (*workspaceResolver, error) {
+	workspace, err := fromContext(ctx).workspace.load(func() (todo.Workspace, error) {
+		return r.services.Workspace.GetById(ctx, viewer.UserId, viewer.WorkspaceId)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &workspaceResolver{root: r, workspace: workspace}, nil
+}
+
+func (r *Resolver) Lists(ctx context.Context) ([]*listResolver, error) {
+	viewer, err := requireScopes(ctx, todo.ScopeListsRead)
+	if err != nil {
+		return nil, err
+	}
+
+	lists, err := r.services.TodoList.GetAll(ctx, viewer.UserId, viewer.WorkspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	listIds := make([]int, 0, len(lists))
+	resolvers := make([]*listResolver, 0, len(lists))
+	for _, list := range lists {
+		listIds = append(listIds, list.Id)
+		resolvers = append(resolvers, &listResolver{root: r, list: list})
+	}
+	fromContext(ctx).items.prime(listIds...)
+
+	return resolvers, nil
+}
+
+func (r *Resolver) List(ctx context.Context, args struct{ Id int32 }) (*listResolver, error) {
+	viewer, err := requireScopes(ctx, todo.ScopeListsRead)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := r.services.TodoList.GetById(ctx, viewer.UserId, viewer.WorkspaceId, int(args.Id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &listResolver{root: r, list: list}, nil
+}
+
+func (r *Resolver) Item(ctx context.Context, args struct{ Id int32 }) (*itemResolver, error) {
+	viewer, err := requireScopes(ctx, todo.ScopeItemsRead)
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := r.services.TodoItem.GetById(ctx, viewer.UserId, viewer.WorkspaceId, int(args.Id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &itemResolver{item: item}, nil
+}
+
+func (r *Resolver) CreateList(ctx context.Context, args struct{ Input listInput }) (*listResolver, error) {
+	viewer, err := requireScopes(ctx, todo.ScopeListsWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	list := args.Input.list()
+	if err := validate.Struct(&list); err != nil {
+		return nil, inputError(err)
+	}
+
+	list.Id, err = r.services.TodoList.Create(ctx, viewer.UserId, viewer.WorkspaceId, list)
+	if err != nil {
+		return nil, err
+	}
+	list.WorkspaceId = viewer.WorkspaceId
+
+	return &listResolver{root: r, list: list}, nil
+}
+
+func (r *Resolver) UpdateList(ctx context.Context, args struct {
+	Id    int32
+	Input todo.UpdateListInput
+}) (*listResolver, error) {
+	viewer, err := requireScopes(ctx, todo.ScopeListsWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.services.TodoList.Update(ctx, viewer.UserId, viewer.WorkspaceId, int(args.Id), args.Input); err != nil {
+		return nil, inputError(err)
+	}
+
+	list, err := r.services.TodoList.GetById(service.WithPrimary(ctx), viewer.UserId, viewer.WorkspaceId, int(args.Id))
+	if err != nil {
+		return nil, notFound(err, "list")
+	}
+
+	return &listResolver{root: r, list: list}, nil
+}
+
+func (r *Resolver) DeleteList(ctx context.Context, args struct{ Id int32 }) (bool, error) {
+	viewer, err := requireScopes(ctx, todo.ScopeListsWrite)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.services.TodoList.Delete(ctx, viewer.UserId, viewer.WorkspaceId, int(args.Id)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *Resolver) CreateItem(ctx context.Context, args struct {
+	ListId int32
+	Input  listInput
+}) (*itemResolver, error) {
+	viewer, err := requireScopes(ctx, todo.ScopeItemsWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	item := args.Input.item()
+	if err := validate.Struct(&item); err != nil {
+		return nil, inputError(err)
+	}
+
+	item.Id, err = r.services.TodoItem.Create(ctx, viewer.UserId, viewer.WorkspaceId, int(args.ListId), item)
+	if err != nil {
+		return nil, notFound(err, "list")
+	}
+
+	return &itemResolver{item: item}, nil
+}
+
+func (r *Resolver) UpdateItem(ctx context.Context, args struct {
+	Id    int32
+	Input todo.UpdateItemInput
+}) (*itemResolver, error) {
+	viewer, err := requireScopes(ctx, todo.ScopeItemsWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.services.TodoItem.Update(ctx, viewer.UserId, viewer.WorkspaceId, int(args.Id), args.Input); err != nil {
+		return nil, inputError(err)
+	}
+
+	item, err := r.services.TodoItem.GetById(service.WithPrimary(ctx), viewer.UserId, viewer.WorkspaceId, int(args.Id))
+	if err != nil {
+		return nil, notFound(err, "item")
+	}
+
+	return &itemResolver{item: item}, nil
+}
+
+func (r *Resolver) DeleteItem(ctx context.Context, args struct{ Id int32 }) (bool, error) {
+	viewer, err := requireScopes(ctx, todo.ScopeItemsWrite)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.services.TodoItem.Delete(ctx, viewer.UserId, viewer.WorkspaceId, int(args.Id)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func notFound(err error, what string) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return errors.New(what + " not found")
+	}
+
+	return err
+}
+
+type userResolver struct {
+	user todo.User
+}
+
+func (u *userResolver) Id() int32        { return int32(u.user.Id) }
+func (u *userResolver) Name() string     { return u.user.Name }
+func (u *userResolver) Username() string { return u.user.Username }
+func (u *userResolver) Role() string     { return u.user.Role }
+
+type workspaceResolver struct {
+	root      *Resolver
+	workspace todo.Workspace
+}
+
+func (w *workspaceResolver) Id() int32      { return int32(w.workspace.Id) }
+func (w *workspaceResolver) Name() string   { return w.workspace.Name }
+func (w *workspaceResolver) Personal() bool { return w.workspace.Personal }
+func (w *workspaceResolver) Role() string   { return w.workspace.Role }
+
+func (w *workspaceResolver) Members(ctx context.Context) ([]*memberResolver, error) {
+	viewer, err := requireScopes(ctx, todo.ScopeListsRead)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := fromContext(ctx).members.load(w.workspace.Id, func() ([]todo.WorkspaceMember, error) {
+		return w.root.services.Workspace.GetMembers(ctx, viewer.UserId, w.workspace.Id)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resolvers := make([]*memberResolver, 0, len(members))
+	for _, member := range members {
+		resolvers = append(resolvers, &memberResolver{member: member})
+	}
+
+	return resolvers, nil
+}
+
+type memberResolver struct {
+	member todo.WorkspaceMember
+}
+
+func (m *memberResolver) UserId() int32    { return int32(m.member.UserId) }
+func (m *memberResolver) Username() string { return m.member.Username }
+func (m *memberResolver) Role() string     { return m.member.Role }
+
+type listResolver struct {
+	root *Resolver
+	list todo.TodoList
+}
+
+func (l *listResolver) Id() int32            { return int32(l.list.Id) }
+func (l *listResolver) Title() string        { return l.list.Title }
+func (l *listResolver) Description() *string { return l.list.Description }
+
+func (l *listResolver) Workspace(ctx context.Context) (*workspaceResolver, error) {
+	return l.root.workspace(ctx, fromContext(ctx).viewer)
+}
+
+func (l *listResolver) Items(ctx context.Context) ([]*itemResolver, error) {
+	viewer, err := requireScopes(ctx, todo.ScopeItemsRead)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := fromContext(ctx).items.load(l.list.Id, func(listIds []int) (map[int][]todo.TodoItem, error) {
+		return l.root.services.TodoItem.GetAllByLists(ctx, viewer.UserId, viewer.WorkspaceId, listIds)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resolvers := make([]*itemResolver, 0, len(items))
+	for _, item := range items {
+		resolvers = append(resolvers, &itemResolver{item: item})
+	}
+
+	return resolvers, nil
+}
+
+type itemResolver struct {
+	item todo.TodoItem
+}
+
+func (i *itemResolver) Id() int32            { return int32(i.item.Id) }
+func (i *itemResolver) Title() string        { return i.item.Title }
+func (i *itemResolver) Description() *string { return i.item.Description }
+func (i *itemResolver) Done() bool           { return i.item.Done }
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"akhmet.com/rest-api"
+	"github.com/gin-gonic/gin"
+)
+
+type getUsersResponse struct {
+	Data []todo.UserAccount `json:"data"`
+}
+
+func (r getUsersResponse) payload() interface{} { return r.Data }
+
+func (h *Handler) adminGetUsers(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+
+	users, err := h.services.Admin.GetUsers(c.Request.Context(), c.Query("search"), limit, offset)
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondCollection(c, http.StatusOK, getUsersResponse{
+		Data: users,
+	})
+}
+
+func (h *Handler) adminDisableUser(c *gin.Context) {
+	h.adminSetDisabled(c, true)
+}
+
+func (h *Handler) adminEnableUser(c *gin.Context) {
+	h.adminSetDisabled(c, false)
+}
+
+func (h *Handler) adminSetDisabled(c *gin.Context, disabled bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	if err := h.services.Admin.SetDisabled(c.Request.Context(), id, disabled); err != nil {
+		newAdminErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, statusResponse{
+		Status: "ok",
+	})
+}
+
+func (h *Handler) adminForcePasswordReset(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	if err := h.services.Admin.RequirePasswordReset(c.Request.Context(), id); err != nil {
+		newAdminErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, statusResponse{
+		Status: "ok",
+	})
+}
+
+type getUsageResponse struct {
+	Data []todo.UserUsage `json:"data"`
+}
+
+func (r getUsageResponse) payload() interface{} { return r.Data }
+
+func (h *Handler) adminGetUsage(c *gin.Context) {
+	usage, err := h.services.Admin.GetUsage(c.Request.Context())
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondCollection(c, http.StatusOK, getUsageResponse{
+		Data: usage,
+	})
+}
+
+func (h *Handler) adminTransferList(c *gin.Context) {
+	listId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid list id param")
+		return
+	}
+
+	var input todo.TransferListInput
+	if !bindJSON(c, &input) {
+		return
+	}
+
+	if err := h.services.Admin.TransferList(c.Request.Context(), listId, input.UserId); err != nil {
+		newAdminErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, statusResponse{
+		Status: "ok",
+	})
+}
+
+func newAdminErrorResponse(c *gin.Context, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		newErrorResponse(c, http.StatusNotFound, "not found")
+		return
+	}
+
+	newErrorResponse(c, http.StatusInternalServerError, err.Error())
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"akhmet.com/rest-api"
+	"github.com/gin-gonic/gin"
+)
+
+type createApiKeyResponse struct {
+	todo.ApiKey
+	Key string `json:"key"`
+}
+
+func (h *Handler) createApiKey(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	var input todo.CreateApiKeyInput
+	if !bindJSON(c, &input) {
+		return
+	}
+
+	if err := input.Scopes.ValidateSubset(getScopes(c)); err != nil {
+		newErrorResponse(c, http.StatusForbidden, err.Error())
+		return
+	}
+
+	key, plain, err := h.services.ApiKey.Create(c.Request.Context(), userId, input)
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respond(c, http.StatusOK, createApiKeyResponse{
+		ApiKey: key,
+		Key:    plain,
+	})
+}
+
+type getAllApiKeysResponse struct {
+	Data []todo.ApiKey `json:"data"`
+}
+
+func (r getAllApiKeysResponse) payload() interface{} { return r.Data }
+
+func (h *Handler) getAllApiKeys(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	keys, err := h.services.ApiKey.GetAll(c.Request.Context(), userId)
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondCollection(c, http.StatusOK, getAllApiKeysResponse{
+		Data: keys,
+	})
+}
+
+func (h *Handler) deleteApiKey(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	if err := h.services.ApiKey.Delete(c.Request.Context(), userId, id); err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respond(c, http.StatusOK, statusResponse{
+		Status: "ok",
+	})
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCreateApiKeyValidation(t *testing.T) {
+	_, router := newDocsTestHandler()
+
+	tests := []struct {
+		name   string
+		body   string
+		fields []string
+	}{
+		{"missing name and scopes", `{}`, []string{"name", "scopes"}},
+		{"unknown scope", `{"name":"ci","scopes":["lists:read","lists:purge"]}`, []string{"scopes"}},
+		{"account scope", `{"name":"ci","scopes":["account"]}`, []string{"scopes"}},
+		{"expired", `{"name":"ci","scopes":["lists:read"],"expires_at":"2000-01-01T00:00:00Z"}`, []string{"expires_at"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v2/api-keys/", strings.NewReader(test.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(authorizationHeader, "Bearer scopes:account+lists:read")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("status %d, want 422: %s", w.Code, w.Body)
+			}
+
+			var body validationErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+
+			var fields []string
+			for _, fieldError := range body.Errors {
+				fields = append(fields, fieldError.Field)
+			}
+			if !reflect.DeepEqual(fields, test.fields) {
+				t.Errorf("fields = %v, want %v", fields, test.fields)
+			}
+		})
+	}
+}
@@ -1,50 +1,193 @@
 package handler
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/service"
 	"net/http"
 )
 
 func (h *Handler) signUp(c *gin.Context) {
 	var input todo.User
 
-	if err := c.BindJSON(&input); err != nil {
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+	if !bindJSON(c, &input) {
 		return
 	}
 
-	id, err := h.services.Authorization.CreateUser(input)
+	id, err := h.services.Authorization.CreateUser(c.Request.Context(), input)
 	if err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, map[string]interface{}{
+	respond(c, http.StatusOK, map[string]interface{}{
 		"id": id,
 	})
 }
 
 type signInInput struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Username string `json:"username" validate:"trim,required"`
+	Password string `json:"password" validate:"required"`
 }
 
 func (h *Handler) signIn(c *gin.Context) {
 	var input signInInput
 
-	if err := c.BindJSON(&input); err != nil {
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+	if !bindJSON(c, &input) {
 		return
 	}
 
-	token, err := h.services.Authorization.GenerateToken(input.Username, input.Password)
+	user, err := h.services.Authorization.Authenticate(c.Request.Context(), input.Username, input.Password)
 	if err != nil {
+		if errors.Is(err, service.ErrUserDisabled) || errors.Is(err, service.ErrPasswordResetRequired) {
+			newErrorResponse(c, http.StatusForbidden, err.Error())
+			return
+		}
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, map[string]interface{}{
+	h.issueToken(c, user)
+}
+
+// issueToken responds with a token for an authenticated user, or with a
+// two-factor challenge when the user has TOTP enabled.
+func (h *Handler) issueToken(c *gin.Context, user todo.User) {
+	if user.TOTPEnabled {
+		challenge, err := h.services.TwoFactor.GenerateChallengeToken(user.Id)
+		if err != nil {
+			newErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		respond(c, http.StatusOK, map[string]interface{}{
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+		})
+		return
+	}
+
+	token, err := h.services.Authorization.GenerateToken(c.Request.Context(), user.Id, todo.AllScopes)
+	if err != nil {
+		if errors.Is(err, service.ErrUserDisabled) {
+			newErrorResponse(c, http.StatusForbidden, err.Error())
+			return
+		}
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respond(c, http.StatusOK, map[string]interface{}{
+		"token": token,
+	})
+}
+
+type changePasswordInput struct {
+	Username    string `json:"username" validate:"trim,required"`
+	Password    string `json:"password" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=8,max=128"`
+	// Code is a TOTP or recovery code, required when two-factor
+	// authentication is enabled.
+	Code string `json:"code,omitempty" validate:"trim"`
+}
+
+func (h *Handler) changePassword(c *gin.Context) {
+	var input changePasswordInput
+
+	if !bindJSON(c, &input) {
+		return
+	}
+
+	if err := h.services.Authorization.ChangePassword(c.Request.Context(), input.Username, input.Password, input.NewPassword, input.Code); err != nil {
+		if errors.Is(err, service.ErrUserDisabled) {
+			newErrorResponse(c, http.StatusForbidden, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrTwoFactorRequired) || errors.Is(err, service.ErrInvalidTwoFactorCode) {
+			newErrorResponse(c, http.StatusUnauthorized, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrTwoFactorLocked) {
+			newErrorResponse(c, http.StatusTooManyRequests, err.Error())
+			return
+		}
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respond(c, http.StatusOK, statusResponse{
+		Status: "ok",
+	})
+}
+
+type signInTwoFactorInput struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"trim,required"`
+}
+
+func (h *Handler) signInTwoFactor(c *gin.Context) {
+	var input signInTwoFactorInput
+
+	if !bindJSON(c, &input) {
+		return
+	}
+
+	userId, err := h.services.TwoFactor.VerifyChallenge(c.Request.Context(), input.ChallengeToken, input.Code)
+	if err != nil {
+		if errors.Is(err, service.ErrTwoFactorLocked) {
+			newErrorResponse(c, http.StatusTooManyRequests, err.Error())
+			return
+		}
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	token, err := h.services.Authorization.GenerateToken(c.Request.Context(), userId, todo.AllScopes)
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respond(c, http.StatusOK, map[string]interface{}{
+		"token": token,
+	})
+}
+
+func (h *Handler) getJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.services.Authorization.JWKS())
+}
+
+type exchangeTokenInput struct {
+	Scopes todo.Scopes `json:"scopes" validate:"required"`
+}
+
+// exchangeToken issues a token restricted to a subset of the caller's
+// scopes, e.g. a read-only token for a dashboard.
+func (h *Handler) exchangeToken(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	var input exchangeTokenInput
+	if !bindJSON(c, &input) {
+		return
+	}
+
+	if err := input.Scopes.ValidateSubset(getScopes(c)); err != nil {
+		newErrorResponse(c, http.StatusForbidden, err.Error())
+		return
+	}
+
+	token, err := h.services.Authorization.GenerateToken(c.Request.Context(), userId, input.Scopes)
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respond(c, http.StatusOK, map[string]interface{}{
 		"token": token,
 	})
 }
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+
+	"akhmet.com/rest-api/pkg/jsonpatch"
+	"akhmet.com/rest-api/pkg/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+type idResponse struct {
+	Id int `json:"id"`
+}
+
+type tokenResponse struct {
+	Token string `json:"token"`
+}
+
+type signInResponse struct {
+	Token             string `json:"token,omitempty"`
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+var apiInfo = openapi.Info{
+	Title:   "Todo API",
+	Version: "1.0.0",
+}
+
+var workspaceHead = []openapi.Parameter{{
+	Name:        workspaceHeader,
+	Description: "Workspace to operate in, defaults to the personal workspace",
+	Type:        "integer",
+}}
+
+var idempotentHead = []openapi.Parameter{workspaceHead[0], {
+	Name:        idempotencyHeader,
+	Description: "Unique key that makes retries safe, the first response is replayed for 24 hours",
+}}
+
+func patchContent(resource interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		jsonpatch.MergePatchType: resource,
+		jsonpatch.JSONPatchType:  []jsonPatchOperation{},
+	}
+}
+
+func (h *Handler) getOpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, h.spec)
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/service"
+	"github.com/gin-gonic/gin"
+)
+
+// scopeTokens accepts tokens of the form "scopes:<scope>+<scope>" and signs
+// in an admin with exactly those scopes.
+type scopeTokens struct {
+	service.Authorization
+}
+
+func (scopeTokens) ParseToken(ctx context.Context, token string) (todo.Principal, error) {
+	if !strings.HasPrefix(token, "scopes:") {
+		return todo.Principal{}, errors.New("invalid token")
+	}
+
+	scopes := todo.ParseScopes(strings.ReplaceAll(strings.TrimPrefix(token, "scopes:"), "+", " "))
+	return todo.Principal{UserId: 1, Role: todo.RoleAdmin, Scopes: scopes}, nil
+}
+
+type noApiKeys struct {
+	service.ApiKey
+}
+
+func (noApiKeys) IsApiKey(token string) bool {
+	return false
+}
+
+type personalWorkspace struct {
+	service.Workspace
+}
+
+func (personalWorkspace) GetPersonal(ctx context.Context, userId int) (todo.Workspace, error) {
+	return todo.Workspace{Id: 1, Personal: true}, nil
+}
+
+func newDocsTestHandler(opts ...Option) (*Handler, *gin.Engine) {
+	h := NewHandler(&service.Service{
+		Authorization: scopeTokens{},
+		ApiKey:        noApiKeys{},
+		Workspace:     personalWorkspace{},
+		OIDC:          fakeOIDC{},
+	}, opts...)
+
+	return h, h.InitRoutes()
+}
+
+var pathParam = regexp.MustCompile(`:([^/]+)`)
+
+func TestSpecMatchesRoutes(t *testing.T) {
+	h, router := newDocsTestHandler(WithMetrics(http.NotFoundHandler()))
+
+	documented := 0
+	for _, item := range h.spec.Paths {
+		documented += len(item)
+	}
+	if routes := router.Routes(); documented != len(routes) {
+		t.Fatalf("%d operations documented, %d routes registered", documented, len(routes))
+	}
+
+	for _, route := range router.Routes() {
+		path := pathParam.ReplaceAllString(route.Path, "{$1}")
+		if h.spec.Paths[path][strings.ToLower(route.Method)] == nil {
+			t.Errorf("%s %s is not documented", route.Method, route.Path)
+		}
+	}
+
+	for _, path := range []string{"/metrics", "/auth/oidc/login", "/auth/oidc/link"} {
+		if _, ok := h.spec.Paths[path]; !ok {
+			t.Errorf("%s is not documented", path)
+		}
+	}
+
+	h, _ = newDocsTestHandler()
+	if _, ok := h.spec.Paths["/metrics"]; ok {
+		t.Error("/metrics is documented without being routed")
+	}
+}
+
+// TestSpecScopesAreEnforced calls every route with all documented scopes
+// but one, and then with all of them. The first must be rejected for
+// exactly the missing scope and the second must not be rejected for scopes
+// at all, so the documented scopes are the ones the router enforces.
+func TestSpecScopesAreEnforced(t *testing.T) {
+	h, router := newDocsTestHandler(WithMetrics(http.NotFoundHandler()))
+
+	for path, item := range h.spec.Paths {
+		for method, op := range item {
+			method, target := strings.ToUpper(method), strings.NewReplacer("{id}", "1", "{user_id}", "2").Replace(path)
+			name := method + " " + path
+
+			code, _ := serve(router, method, target, "")
+			if public := op.Security == nil; public == (code == http.StatusUnauthorized) {
+				t.Errorf("%s: got %d without a token, documented public: %v", name, code, public)
+			}
+
+			for i, scope := range op.Scopes {
+				granted := append(append([]string(nil), op.Scopes[:i]...), op.Scopes[i+1:]...)
+				code, missing := serve(router, method, target, "scopes:"+strings.Join(granted, "+"))
+				if code != http.StatusForbidden || !reflect.DeepEqual(missing, []string{scope}) {
+					t.Errorf("%s without %s: got %d missing %v", name, scope, code, missing)
+				}
+			}
+
+			if _, missing := serve(router, method, target, "scopes:"+strings.Join(op.Scopes, "+")); missing != nil {
+				t.Errorf("%s with documented scopes %v: missing %v", name, op.Scopes, missing)
+			}
+		}
+	}
+}
+
+func serve(router *gin.Engine, method, target, token string) (int, []string) {
+	req := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		req.Header.Set(authorizationHeader, "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var body insufficientScopeResponse
+	json.Unmarshal(w.Body.Bytes(), &body)
+
+	return w.Code, body.MissingScopes
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Features are switches that can be flipped while the server runs.
+type Features struct {
+	// SignUp allows creating accounts with /auth/sign-up.
+	SignUp bool
+}
+
+// DefaultFeatures are used until WithFeatures or SetFeatures says otherwise.
+var DefaultFeatures = Features{SignUp: true}
+
+// WithFeatures sets the features the handler starts with.
+func WithFeatures(features Features) Option {
+	return func(h *Handler) {
+		h.features.Store(features)
+	}
+}
+
+// SetFeatures replaces the features of a running handler.
+func (h *Handler) SetFeatures(features Features) {
+	h.features.Store(features)
+}
+
+func (h *Handler) getFeatures() Features {
+	return h.features.Load().(Features)
+}
+
+// requireFeature rejects requests with 403 while enabled reports the
+// feature as off.
+func (h *Handler) requireFeature(name string, enabled func(Features) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !enabled(h.getFeatures()) {
+			newErrorResponse(c, http.StatusForbidden, name+" is disabled")
+		}
+	}
+}
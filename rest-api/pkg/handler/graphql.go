@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"akhmet.com/rest-api/pkg/graph"
+	"github.com/gin-gonic/gin"
+)
+
+type graphQLRequest struct {
+	Query         string                 `json:"query" validate:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+type graphQLResponse struct {
+	Data   interface{}   `json:"data,omitempty"`
+	Errors []interface{} `json:"errors,omitempty"`
+}
+
+func (h *Handler) graphQL(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	workspaceId, err := getWorkspaceId(c)
+	if err != nil {
+		return
+	}
+
+	var input graphQLRequest
+	if !bindJSON(c, &input) {
+		return
+	}
+
+	ctx := graph.WithViewer(c.Request.Context(), graph.Viewer{
+		UserId:      userId,
+		WorkspaceId: workspaceId,
+		Scopes:      getScopes(c),
+	})
+
+	response := h.graph.Exec(ctx, input.Query, input.OperationName, input.Variables)
+	body, err := json.Marshal(response)
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
@@ -1,51 +1,198 @@
 package handler
 
 import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/compress"
+	"akhmet.com/rest-api/pkg/graph"
+	"akhmet.com/rest-api/pkg/metrics"
+	"akhmet.com/rest-api/pkg/openapi"
 	"akhmet.com/rest-api/pkg/service"
+	"akhmet.com/rest-api/pkg/tracing"
+	"github.com/graph-gophers/graphql-go"
 )
 
 type Handler struct {
 	services *service.Service
+	spec     *openapi.Document
+	graph    *graphql.Schema
+
+	metricsHandler http.Handler
+	v1DeprecatedAt time.Time
+	v1Sunset       time.Time
+
+	limiter  *rateLimiter
+	features atomic.Value
+}
+
+// Option configures optional routes of the handler.
+type Option func(*Handler)
+
+// WithMetrics serves metrics on /metrics next to the api. Leave it out
+// when metrics are served on a port of their own.
+func WithMetrics(metricsHandler http.Handler) Option {
+	return func(h *Handler) {
+		h.metricsHandler = metricsHandler
+	}
 }
 
-func NewHandler(services *service.Service) *Handler {
-	return &Handler{services: services}
+func NewHandler(services *service.Service, opts ...Option) *Handler {
+	h := &Handler{services: services, graph: graph.NewSchema(services), limiter: newRateLimiter()}
+	h.features.Store(DefaultFeatures)
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
 }
 
 func (h *Handler) InitRoutes() *gin.Engine {
 	router := gin.New()
+	router.Use(tracing.Middleware, requestLogging, recovery, metrics.Middleware, compress.Middleware(compress.DefaultMinLength))
+
+	root := h.newRouteGroup(router)
+	limited := root.RateLimited()
 
-	auth := router.Group("/auth")
+	root.GET(openapi.Operation{Path: "/openapi.json", Summary: "OpenAPI document", Tags: []string{"docs"}}, h.getOpenAPI)
+	root.GET(openapi.Operation{Path: "/docs", Summary: "Swagger UI", Tags: []string{"docs"}}, openapi.SwaggerUI("/openapi.json"))
+	root.GET(openapi.Operation{Path: "/.well-known/jwks.json", Summary: "Token verification keys", Tags: []string{"auth"}, Response: service.JWKSet{}}, h.getJWKS)
+	limited.Authenticated().POST(openapi.Operation{Path: "/graphql", Summary: "GraphQL endpoint", Tags: []string{"graphql"}, Headers: workspaceHead, Request: graphQLRequest{}, Response: graphQLResponse{},
+		Description: "Fields check the same scopes as the matching REST routes. Missing scopes are reported per field in the errors array."},
+		h.workspaceIdentity, h.graphQL)
+
+	root.GET(openapi.Operation{Path: "/healthz", Summary: "Liveness probe", Description: "Succeeds while the process is up; dependencies are not checked.", Tags: []string{"operations"}, Response: statusResponse{}}, h.getHealthz)
+	root.GET(openapi.Operation{Path: "/readyz", Summary: "Readiness probe", Tags: []string{"operations"}, Response: todo.Readiness{}, Errors: []int{http.StatusServiceUnavailable},
+		Description: "Fails with 503 while the database is unreachable or not fully migrated, and from the moment shutdown begins."},
+		h.getReadyz)
+	root.Authenticated().RequireScopes(todo.ScopeAccount).GET(openapi.Operation{Path: "/debug/status", Summary: "Build, uptime and database pool status", Tags: []string{"operations"}, Response: todo.ServiceStatus{}},
+		h.requireRole(todo.RoleAdmin), h.getDebugStatus)
+
+	if h.metricsHandler != nil {
+		root.GET(openapi.Operation{Path: "/metrics", Summary: "Prometheus metrics", Tags: []string{"operations"}}, gin.WrapH(h.metricsHandler))
+	}
+
+	auth := limited.Group("/auth")
 	{
-		auth.POST("/sign-up", h.signUp)
-		auth.POST("/sign-in", h.signIn)
+		auth.POST(openapi.Operation{Path: "/sign-up", Summary: "Create an account", Tags: []string{"auth"}, Request: todo.User{}, Response: idResponse{},
+			Description: "Fails with 403 while sign-up is switched off.", Errors: []int{http.StatusForbidden}},
+			h.requireFeature("sign-up", func(f Features) bool { return f.SignUp }), h.signUp)
+		auth.POST(openapi.Operation{Path: "/sign-in", Summary: "Sign in with username and password", Tags: []string{"auth"}, Request: signInInput{}, Response: signInResponse{},
+			Description: "Returns a challenge token instead of an access token when two-factor authentication is enabled.", Errors: []int{http.StatusForbidden}},
+			h.signIn)
+		auth.POST(openapi.Operation{Path: "/sign-in/2fa", Summary: "Complete a two-factor challenge", Tags: []string{"auth"}, Request: signInTwoFactorInput{}, Response: tokenResponse{}, Errors: []int{http.StatusUnauthorized},
+			Description: "Five invalid codes in a row lock two-factor verification of the account for 15 minutes, answered with 429."},
+			h.signInTwoFactor)
+		auth.POST(openapi.Operation{Path: "/change-password", Summary: "Change password", Tags: []string{"auth"}, Request: changePasswordInput{}, Response: statusResponse{}, Errors: []int{http.StatusUnauthorized, http.StatusForbidden},
+			Description: "Accounts with two-factor authentication also need a TOTP or recovery code. Invalid codes count towards the same lockout as /auth/sign-in/2fa."},
+			h.changePassword)
+
+		if h.services.OIDC != nil {
+			auth.GET(openapi.Operation{Path: "/oidc/login", Summary: "Start single sign-on", Tags: []string{"auth"}}, h.oidcLogin)
+			auth.GET(openapi.Operation{Path: "/oidc/callback", Summary: "Finish single sign-on", Tags: []string{"auth"}, Response: signInResponse{},
+				Description: "Returns a challenge token instead of an access token when two-factor authentication is enabled. After /auth/oidc/link the identity is linked to that account.",
+				Query:       []openapi.Parameter{{Name: "code", Required: true}, {Name: "state", Required: true}}, Errors: []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusConflict}},
+				h.oidcCallback)
+			auth.Authenticated().POST(openapi.Operation{Path: "/oidc/link", Summary: "Start linking single sign-on to this account", Tags: []string{"auth"}, Scopes: []string{todo.ScopeAccount}, Response: oidcLinkResponse{},
+				Description: "Sets the login state cookie and returns the provider URL to send the browser to. Accounts are only ever linked this way, never by matching email."},
+				h.oidcLink)
+		}
 	}
 
-	api := router.Group("/api", h.userIdentity)
+	admin := limited.Group("/admin").Authenticated().RequireScopes(todo.ScopeAccount).Group("", h.requireRole(todo.RoleAdmin))
 	{
-		lists := api.Group("/lists")
+		users := admin.Group("/users")
 		{
-			lists.POST("/", h.createList)
-			lists.GET("/", h.getAllLists)
-			lists.GET("/:id", h.getListById)
-			lists.PUT("/:id", h.updateList)
-			lists.DELETE("/:id", h.deleteList)
-
-			items := lists.Group(":id/items")
-			{
-				items.POST("/", h.createItem)
-				items.GET("/", h.getAllItems)
-			}
+			users.GET(openapi.Operation{Path: "/", Summary: "List users", Tags: []string{"admin"}, Response: getUsersResponse{},
+				Query:         []openapi.Parameter{{Name: "search"}, {Name: "limit", Type: "integer"}, {Name: "offset", Type: "integer"}},
+				ResponseTypes: collectionTypes[1:], Errors: []int{http.StatusNotAcceptable}},
+				h.adminGetUsers)
+			users.POST(openapi.Operation{Path: "/:id/disable", Summary: "Disable an account", Tags: []string{"admin"}, Response: statusResponse{}, Errors: []int{http.StatusNotFound}}, h.adminDisableUser)
+			users.POST(openapi.Operation{Path: "/:id/enable", Summary: "Enable an account", Tags: []string{"admin"}, Response: statusResponse{}, Errors: []int{http.StatusNotFound}}, h.adminEnableUser)
+			users.POST(openapi.Operation{Path: "/:id/force-password-reset", Summary: "Require a password change", Tags: []string{"admin"}, Response: statusResponse{}, Errors: []int{http.StatusNotFound}}, h.adminForcePasswordReset)
 		}
 
-		items := api.Group("/items")
+		admin.GET(openapi.Operation{Path: "/stats", Summary: "Lists and items per user", Tags: []string{"admin"}, Response: getUsageResponse{}, ResponseTypes: collectionTypes[1:], Errors: []int{http.StatusNotAcceptable}}, h.adminGetUsage)
+		admin.POST(openapi.Operation{Path: "/lists/:id/transfer", Summary: "Transfer list ownership", Tags: []string{"admin"}, Request: todo.TransferListInput{}, Response: statusResponse{}, Errors: []int{http.StatusNotFound}}, h.adminTransferList)
+	}
+
+	for _, v := range apiVersions {
+		h.initAPIRoutes(limited.Group(v.prefix).Version(v.version).Authenticated())
+	}
+
+	spec, err := openapi.Build(apiInfo, router.Routes(), *root.operations, errorResponse{}, validationErrorResponse{})
+	if err != nil {
+		panic(err)
+	}
+	h.spec = spec
+
+	return router
+}
+
+// initAPIRoutes registers the /api routes under one version prefix. Handlers
+// are shared between versions and only the response shape differs.
+func (h *Handler) initAPIRoutes(api routeGroup) {
+	api.POST(openapi.Operation{Path: "/tokens", Summary: "Issue a token with fewer scopes", Tags: []string{"auth"}, Scopes: []string{todo.ScopeAccount}, Request: exchangeTokenInput{}, Response: tokenResponse{}}, h.exchangeToken)
+
+	twoFactor := api.Group("/2fa").RequireScopes(todo.ScopeAccount)
+	{
+		twoFactor.POST(openapi.Operation{Path: "/enroll", Summary: "Start TOTP enrollment", Tags: []string{"2fa"}, Response: todo.TOTPEnrollment{}, Errors: []int{http.StatusConflict}}, h.enrollTwoFactor)
+		twoFactor.POST(openapi.Operation{Path: "/confirm", Summary: "Confirm TOTP enrollment", Tags: []string{"2fa"}, Request: confirmTwoFactorInput{}, Response: recoveryCodesResponse{}, Errors: []int{http.StatusConflict}}, h.confirmTwoFactor)
+	}
+
+	apiKeys := api.Group("/api-keys").RequireScopes(todo.ScopeAccount)
+	{
+		apiKeys.POST(openapi.Operation{Path: "/", Summary: "Create an api key", Description: "The key is only returned once.", Tags: []string{"api-keys"}, Request: todo.CreateApiKeyInput{}, Response: createApiKeyResponse{}}, h.createApiKey)
+		apiKeys.GET(openapi.Operation{Path: "/", Summary: "List api keys", Tags: []string{"api-keys"}, Response: getAllApiKeysResponse{}, ResponseTypes: collectionTypes[1:], Errors: []int{http.StatusNotAcceptable}}, h.getAllApiKeys)
+		apiKeys.DELETE(openapi.Operation{Path: "/:id", Summary: "Revoke an api key", Tags: []string{"api-keys"}, Response: statusResponse{}}, h.deleteApiKey)
+	}
+
+	workspaces := api.Group("/workspaces").RequireScopes(todo.ScopeListsRead)
+	{
+		workspaces.POST(openapi.Operation{Path: "/", Summary: "Create a workspace", Tags: []string{"workspaces"}, Scopes: []string{todo.ScopeListsWrite}, Request: todo.Workspace{}, Response: idResponse{}}, h.createWorkspace)
+		workspaces.GET(openapi.Operation{Path: "/", Summary: "List workspaces", Tags: []string{"workspaces"}, Response: getAllWorkspacesResponse{}, ResponseTypes: collectionTypes[1:], Errors: []int{http.StatusNotAcceptable}}, h.getAllWorkspaces)
+		workspaces.GET(openapi.Operation{Path: "/:id/members", Summary: "List workspace members", Tags: []string{"workspaces"}, Response: getWorkspaceMembersResponse{}, ResponseTypes: collectionTypes[1:], Errors: []int{http.StatusNotFound, http.StatusNotAcceptable}}, h.getWorkspaceMembers)
+		workspaces.POST(openapi.Operation{Path: "/:id/members", Summary: "Add a workspace member", Tags: []string{"workspaces"}, Scopes: []string{todo.ScopeAccount}, Request: todo.AddWorkspaceMemberInput{}, Response: statusResponse{}, Errors: []int{http.StatusNotFound}}, h.addWorkspaceMember)
+		workspaces.DELETE(openapi.Operation{Path: "/:id/members/:user_id", Summary: "Remove a workspace member", Tags: []string{"workspaces"}, Scopes: []string{todo.ScopeAccount}, Response: statusResponse{},
+			Description: "Members can remove themselves. Nobody can leave their personal workspace, and the last owner cannot leave a shared one.",
+			Errors:      []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict}},
+			h.removeWorkspaceMember)
+	}
+
+	lists := api.Group("/lists", h.workspaceIdentity)
+	{
+		lists.POST(openapi.Operation{Path: "/", Summary: "Create a list", Tags: []string{"lists"}, Scopes: []string{todo.ScopeListsWrite}, Headers: idempotentHead, Request: todo.TodoList{}, Response: idResponse{},
+			Errors: []int{http.StatusConflict, http.StatusUnprocessableEntity}},
+			h.idempotent, h.createList)
+		lists.GET(openapi.Operation{Path: "/", Summary: "List lists", Tags: []string{"lists"}, Scopes: []string{todo.ScopeListsRead}, Headers: workspaceHead, Response: getAllListsResponse{}, ResponseTypes: collectionTypes[1:], Errors: []int{http.StatusNotAcceptable}}, h.getAllLists)
+		lists.GET(openapi.Operation{Path: "/:id", Summary: "Get a list", Tags: []string{"lists"}, Scopes: []string{todo.ScopeListsRead}, Headers: workspaceHead, Response: todo.TodoList{}}, h.getListById)
+		lists.PUT(openapi.Operation{Path: "/:id", Summary: "Update a list", Tags: []string{"lists"}, Scopes: []string{todo.ScopeListsWrite}, Headers: workspaceHead, Request: todo.UpdateListInput{}, Response: statusResponse{}}, h.updateList)
+		lists.PATCH(openapi.Operation{Path: "/:id", Summary: "Patch a list", Tags: []string{"lists"}, Scopes: []string{todo.ScopeListsWrite}, Headers: workspaceHead, Response: todo.TodoList{},
+			Description: "Accepts a JSON Merge Patch or a JSON Patch. Setting description to null clears it.", RequestContent: patchContent(todo.TodoList{}),
+			Errors: []int{http.StatusNotFound, http.StatusConflict, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity}},
+			h.patchList)
+		lists.DELETE(openapi.Operation{Path: "/:id", Summary: "Delete a list", Tags: []string{"lists"}, Scopes: []string{todo.ScopeListsWrite}, Headers: workspaceHead, Response: statusResponse{}}, h.deleteList)
+
+		items := lists.Group(":id/items")
 		{
-			items.GET("/:id", h.getItemById)
-			items.PUT("/:id", h.updateItem)
-			items.DELETE("/:id", h.deleteItem)
+			items.POST(openapi.Operation{Path: "/", Summary: "Create an item", Tags: []string{"items"}, Scopes: []string{todo.ScopeItemsWrite}, Headers: idempotentHead, Request: todo.TodoItem{}, Response: idResponse{},
+				Errors: []int{http.StatusConflict, http.StatusUnprocessableEntity}},
+				h.idempotent, h.createItem)
+			items.GET(openapi.Operation{Path: "/", Summary: "List items of a list", Tags: []string{"items"}, Scopes: []string{todo.ScopeItemsRead}, Headers: workspaceHead, Response: []todo.TodoItem{}, ResponseTypes: collectionTypes[1:], Errors: []int{http.StatusNotAcceptable}}, h.getAllItems)
 		}
 	}
 
-	return router
+	items := api.Group("/items", h.workspaceIdentity)
+	{
+		items.GET(openapi.Operation{Path: "/:id", Summary: "Get an item", Tags: []string{"items"}, Scopes: []string{todo.ScopeItemsRead}, Headers: workspaceHead, Response: todo.TodoItem{}}, h.getItemById)
+		items.PUT(openapi.Operation{Path: "/:id", Summary: "Update an item", Tags: []string{"items"}, Scopes: []string{todo.ScopeItemsWrite}, Headers: workspaceHead, Request: todo.UpdateItemInput{}, Response: statusResponse{}}, h.updateItem)
+		items.PATCH(openapi.Operation{Path: "/:id", Summary: "Patch an item", Tags: []string{"items"}, Scopes: []string{todo.ScopeItemsWrite}, Headers: workspaceHead, Response: todo.TodoItem{},
+			Description: "Accepts a JSON Merge Patch or a JSON Patch. Setting description to null clears it.", RequestContent: patchContent(todo.TodoItem{}),
+			Errors: []int{http.StatusNotFound, http.StatusConflict, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity}},
+			h.patchItem)
+		items.DELETE(openapi.Operation{Path: "/:id", Summary: "Delete an item", Tags: []string{"items"}, Scopes: []string{todo.ScopeItemsWrite}, Headers: workspaceHead, Response: statusResponse{}}, h.deleteItem)
+	}
 }
@@ -0,0 +1,13 @@
+package handler
+
+import "github.com/gin-gonic/gin"
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func newTestRouter() *gin.Engine {
+	router := gin.New()
+	router.Use(recovery)
+	return router
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"net/http"
+
+	"akhmet.com/rest-api"
+	"github.com/gin-gonic/gin"
+)
+
+// probeRoutes are polled by the orchestrator every few seconds. Their
+// successful requests are only logged at debug level and failures, which
+// are expected during shutdown, as warnings.
+var probeRoutes = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+}
+
+// getHealthz reports that the process is up and serving requests. It
+// checks no dependencies, so a database outage does not get the instance
+// restarted.
+func (h *Handler) getHealthz(c *gin.Context) {
+	c.JSON(http.StatusOK, statusResponse{Status: todo.HealthStatusOK})
+}
+
+// getReadyz reports whether the instance should receive traffic. It fails
+// while the database is unreachable or not migrated and once shutdown has
+// begun.
+func (h *Handler) getReadyz(c *gin.Context) {
+	readiness := h.services.Health.Ready(c.Request.Context())
+
+	status := http.StatusOK
+	if readiness.Status != todo.HealthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, readiness)
+}
+
+func (h *Handler) getDebugStatus(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, h.services.Health.Status(c.Request.Context()))
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"akhmet.com/rest-api/pkg/service"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	idempotencyHeader    = "Idempotency-Key"
+	idempotencyKeyMaxLen = 255
+)
+
+// recordingWriter keeps a copy of the response body so it can be stored
+// for replay.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// idempotent makes a POST route safe to retry. The first response for an
+// Idempotency-Key is stored per user and replayed for later requests with
+// the same key and body. Reusing the key with a different body is rejected
+// with 422. Server errors are not stored so that the request can be retried.
+func (h *Handler) idempotent(c *gin.Context) {
+	key := c.GetHeader(idempotencyHeader)
+	if key == "" {
+		return
+	}
+
+	if len(key) > idempotencyKeyMaxLen {
+		newErrorResponse(c, http.StatusBadRequest, "idempotency key is too long")
+		return
+	}
+
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	record, err := h.services.Idempotency.Begin(c.Request.Context(), userId, key, requestFingerprint(c, body))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrIdempotencyKeyReused):
+			newErrorResponse(c, http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, service.ErrIdempotencyKeyInProgress):
+			newErrorResponse(c, http.StatusConflict, err.Error())
+		default:
+			newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	if record != nil {
+		c.Header("Idempotent-Replayed", "true")
+		c.Data(record.StatusCode, record.ContentType, record.Body)
+		c.Abort()
+		return
+	}
+
+	// A panicking handler unwinds past the code below to the recovery
+	// middleware, so the reservation is released here, or retries would be
+	// rejected as in progress until the key expires.
+	finished := false
+	defer func() {
+		if finished {
+			return
+		}
+		if err := h.services.Idempotency.Abort(c.Request.Context(), userId, key); err != nil {
+			requestLogger(c).Errorf("failed to release idempotency key: %s", err.Error())
+		}
+	}()
+
+	writer := &recordingWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+	c.Next()
+	finished = true
+
+	if writer.Status() >= http.StatusInternalServerError {
+		err = h.services.Idempotency.Abort(c.Request.Context(), userId, key)
+	} else {
+		err = h.services.Idempotency.Complete(c.Request.Context(), userId, key, writer.Status(), writer.Header().Get("Content-Type"), writer.body.Bytes())
+	}
+	if err != nil {
+		requestLogger(c).Errorf("failed to store idempotent response: %s", err.Error())
+	}
+}
+
+// requestFingerprint identifies a request by route, workspace and body, so
+// a key reused for anything else is detected.
+func requestFingerprint(c *gin.Context, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+	hash.Write([]byte(strconv.Itoa(c.GetInt(workspaceCtx)) + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/service"
+	"github.com/gin-gonic/gin"
+)
+
+type recordingIdempotency struct {
+	service.Idempotency
+	completed, aborted []string
+}
+
+func (r *recordingIdempotency) Begin(ctx context.Context, userId int, key, fingerprint string) (*todo.IdempotencyRecord, error) {
+	return nil, nil
+}
+
+func (r *recordingIdempotency) Complete(ctx context.Context, userId int, key string, statusCode int, contentType string, body []byte) error {
+	r.completed = append(r.completed, key)
+	return nil
+}
+
+func (r *recordingIdempotency) Abort(ctx context.Context, userId int, key string) error {
+	r.aborted = append(r.aborted, key)
+	return nil
+}
+
+func TestIdempotentReleasesKeyAfterPanic(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		handler   gin.HandlerFunc
+		status    int
+		completed bool
+	}{
+		{"panic", func(c *gin.Context) { panic("boom") }, http.StatusInternalServerError, false},
+		{"server error", func(c *gin.Context) { newErrorResponse(c, http.StatusInternalServerError, "failed") }, http.StatusInternalServerError, false},
+		{"success", func(c *gin.Context) { respond(c, http.StatusOK, idResponse{Id: 1}) }, http.StatusOK, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			idempotency := &recordingIdempotency{}
+			h := &Handler{services: &service.Service{Idempotency: idempotency}}
+
+			router := newTestRouter()
+			router.POST("/lists", func(c *gin.Context) { c.Set(userCtx, 1) }, h.idempotent, tt.handler)
+
+			req := httptest.NewRequest(http.MethodPost, "/lists", strings.NewReader(`{"title":"groceries"}`))
+			req.Header.Set(idempotencyHeader, "key-1")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d", w.Code, tt.status)
+			}
+			if completed := len(idempotency.completed) == 1; completed != tt.completed {
+				t.Fatalf("completed %v, want %v", idempotency.completed, tt.completed)
+			}
+			if released := len(idempotency.aborted) == 1; released == tt.completed || len(idempotency.aborted) > 1 {
+				t.Fatalf("released %v", idempotency.aborted)
+			}
+		})
+	}
+}
@@ -13,6 +13,11 @@ func (h *Handler) createItem(c *gin.Context) {
 		return
 	}
 
+	workspaceId, err := getWorkspaceId(c)
+	if err != nil {
+		return
+	}
+
 	listId, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		newErrorResponse(c, http.StatusBadRequest, "invalid list id param")
@@ -20,18 +25,17 @@ func (h *Handler) createItem(c *gin.Context) {
 	}
 
 	var input todo.TodoItem
-	if err := c.BindJSON(&input); err != nil {
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+	if !bindJSON(c, &input) {
 		return
 	}
 
-	id, err := h.services.TodoItem.Create(userId, listId, input)
+	id, err := h.services.TodoItem.Create(c.Request.Context(), userId, workspaceId, listId, input)
 	if err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, map[string]interface{}{
+	respond(c, http.StatusOK, map[string]interface{}{
 		"id": id,
 	})
 }
@@ -42,19 +46,24 @@ func (h *Handler) getAllItems(c *gin.Context) {
 		return
 	}
 
+	workspaceId, err := getWorkspaceId(c)
+	if err != nil {
+		return
+	}
+
 	listId, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		newErrorResponse(c, http.StatusBadRequest, "invalid list id param")
 		return
 	}
 
-	item, err := h.services.TodoItem.GetAll(userId, listId)
+	item, err := h.services.TodoItem.GetAll(c.Request.Context(), userId, workspaceId, listId)
 	if err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, item)
+	respondCollection(c, http.StatusOK, item)
 }
 
 func (h *Handler) getItemById(c *gin.Context) {
@@ -63,19 +72,24 @@ func (h *Handler) getItemById(c *gin.Context) {
 		return
 	}
 
+	workspaceId, err := getWorkspaceId(c)
+	if err != nil {
+		return
+	}
+
 	itemId, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		newErrorResponse(c, http.StatusBadRequest, "invalid list id param")
 		return
 	}
 
-	item, err := h.services.TodoItem.GetById(userId, itemId)
+	item, err := h.services.TodoItem.GetById(c.Request.Context(), userId, workspaceId, itemId)
 	if err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, item)
+	respond(c, http.StatusOK, item)
 }
 
 func (h *Handler) deleteItem(c *gin.Context) {
@@ -84,19 +98,24 @@ func (h *Handler) deleteItem(c *gin.Context) {
 		return
 	}
 
+	workspaceId, err := getWorkspaceId(c)
+	if err != nil {
+		return
+	}
+
 	itemId, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
 		return
 	}
 
-	err = h.services.TodoItem.Delete(userId, itemId)
+	err = h.services.TodoItem.Delete(c.Request.Context(), userId, workspaceId, itemId)
 	if err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, statusResponse{
+	respond(c, http.StatusOK, statusResponse{
 		Status: "ok",
 	})
 }
@@ -107,6 +126,11 @@ func (h *Handler) updateItem(c *gin.Context) {
 		return
 	}
 
+	workspaceId, err := getWorkspaceId(c)
+	if err != nil {
+		return
+	}
+
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
@@ -114,17 +138,47 @@ func (h *Handler) updateItem(c *gin.Context) {
 	}
 
 	var input todo.UpdateItemInput
-	if err := c.BindJSON(&input); err != nil {
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+	if !bindJSON(c, &input) {
 		return
 	}
 
-	if err := h.services.TodoItem.Update(userId, id, input); err != nil {
+	if err := h.services.TodoItem.Update(c.Request.Context(), userId, workspaceId, id, input); err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, statusResponse{
+	respond(c, http.StatusOK, statusResponse{
 		Status: "ok",
 	})
 }
+
+func (h *Handler) patchItem(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	workspaceId, err := getWorkspaceId(c)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	patch, err := decodePatch(c)
+	if err != nil {
+		return
+	}
+
+	item, err := h.services.TodoItem.Patch(c.Request.Context(), userId, workspaceId, id, patch)
+	if err != nil {
+		newPatchErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, item)
+}
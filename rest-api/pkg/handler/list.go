@@ -13,19 +13,23 @@ func (h *Handler) createList(c *gin.Context) {
 		return
 	}
 
+	workspaceId, err := getWorkspaceId(c)
+	if err != nil {
+		return
+	}
+
 	var input todo.TodoList
-	if err := c.BindJSON(&input); err != nil {
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+	if !bindJSON(c, &input) {
 		return
 	}
 
-	id, err := h.services.TodoList.Create(userId, input)
+	id, err := h.services.TodoList.Create(c.Request.Context(), userId, workspaceId, input)
 	if err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, map[string]interface{}{
+	respond(c, http.StatusOK, map[string]interface{}{
 		"id": id,
 	})
 }
@@ -34,19 +38,26 @@ type getAllListsResponse struct {
 	Data []todo.TodoList `json:"data"`
 }
 
+func (r getAllListsResponse) payload() interface{} { return r.Data }
+
 func (h *Handler) getAllLists(c *gin.Context) {
 	userId, err := getUserId(c)
 	if err != nil {
 		return
 	}
 
-	lists, err := h.services.TodoList.GetAll(userId)
+	workspaceId, err := getWorkspaceId(c)
+	if err != nil {
+		return
+	}
+
+	lists, err := h.services.TodoList.GetAll(c.Request.Context(), userId, workspaceId)
 	if err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, getAllListsResponse{
+	respondCollection(c, http.StatusOK, getAllListsResponse{
 		Data: lists,
 	})
 }
@@ -57,19 +68,24 @@ func (h *Handler) getListById(c *gin.Context) {
 		return
 	}
 
+	workspaceId, err := getWorkspaceId(c)
+	if err != nil {
+		return
+	}
+
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
 		return
 	}
 
-	list, err := h.services.TodoList.GetById(userId, id)
+	list, err := h.services.TodoList.GetById(c.Request.Context(), userId, workspaceId, id)
 	if err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, list)
+	respond(c, http.StatusOK, list)
 }
 
 func (h *Handler) deleteList(c *gin.Context) {
@@ -78,19 +94,24 @@ func (h *Handler) deleteList(c *gin.Context) {
 		return
 	}
 
+	workspaceId, err := getWorkspaceId(c)
+	if err != nil {
+		return
+	}
+
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
 		return
 	}
 
-	err = h.services.TodoList.Delete(userId, id)
+	err = h.services.TodoList.Delete(c.Request.Context(), userId, workspaceId, id)
 	if err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, statusResponse{
+	respond(c, http.StatusOK, statusResponse{
 		Status: "ok",
 	})
 }
@@ -101,6 +122,11 @@ func (h *Handler) updateList(c *gin.Context) {
 		return
 	}
 
+	workspaceId, err := getWorkspaceId(c)
+	if err != nil {
+		return
+	}
+
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
@@ -108,17 +134,47 @@ func (h *Handler) updateList(c *gin.Context) {
 	}
 
 	var input todo.UpdateListInput
-	if err := c.BindJSON(&input); err != nil {
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+	if !bindJSON(c, &input) {
 		return
 	}
 
-	if err := h.services.TodoList.Update(userId, id, input); err != nil {
+	if err := h.services.TodoList.Update(c.Request.Context(), userId, workspaceId, id, input); err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, statusResponse{
+	respond(c, http.StatusOK, statusResponse{
 		Status: "ok",
 	})
 }
+
+func (h *Handler) patchList(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	workspaceId, err := getWorkspaceId(c)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	patch, err := decodePatch(c)
+	if err != nil {
+		return
+	}
+
+	list, err := h.services.TodoList.Patch(c.Request.Context(), userId, workspaceId, id, patch)
+	if err != nil {
+		newPatchErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, list)
+}
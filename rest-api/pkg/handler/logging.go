@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"io/ioutil"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"akhmet.com/rest-api/pkg/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// requestLogging assigns every request an id, puts a logger carrying it
+// into the request context and writes one access log entry per request
+// once it completes.
+func requestLogging(c *gin.Context) {
+	start := time.Now()
+
+	requestId := logging.RequestID(c.GetHeader(logging.RequestIDHeader))
+	c.Header(logging.RequestIDHeader, requestId)
+
+	fields := logrus.Fields{"request_id": requestId}
+	for key, value := range logging.TraceFields(c.Request.Context()) {
+		fields[key] = value
+	}
+	withLogFields(c, fields)
+
+	c.Next()
+
+	path := c.Request.URL.Path
+	if query := logging.RedactQuery(c.Request.URL.RawQuery); query != "" {
+		path += "?" + query
+	}
+
+	bytesOut := c.Writer.Size()
+	if bytesOut < 0 {
+		bytesOut = 0
+	}
+
+	status := c.Writer.Status()
+	level := logrus.InfoLevel
+	switch {
+	case probeRoutes[c.FullPath()] && status == http.StatusOK:
+		level = logrus.DebugLevel
+	case probeRoutes[c.FullPath()]:
+		level = logrus.WarnLevel
+	case status >= http.StatusInternalServerError:
+		level = logrus.ErrorLevel
+	case status >= http.StatusBadRequest:
+		level = logrus.WarnLevel
+	}
+
+	requestLogger(c).WithFields(logrus.Fields{
+		"method":     c.Request.Method,
+		"route":      c.FullPath(),
+		"path":       path,
+		"status":     status,
+		"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+		"bytes_in":   c.Request.ContentLength,
+		"bytes_out":  bytesOut,
+		"client_ip":  c.ClientIP(),
+		"user_agent": c.Request.UserAgent(),
+	}).Log(level, "request completed")
+}
+
+// recovery turns a panicking handler into a logged 500 response.
+var recovery = gin.CustomRecoveryWithWriter(ioutil.Discard, func(c *gin.Context, err interface{}) {
+	requestLogger(c).WithFields(logrus.Fields{
+		"panic": err,
+		"stack": string(debug.Stack()),
+	}).Error("handler panicked")
+
+	c.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse{"internal server error"})
+})
+
+// requestLogger returns the logger of the request, carrying its id and,
+// once authenticated, the caller.
+func requestLogger(c *gin.Context) *logrus.Entry {
+	return logging.FromContext(c.Request.Context())
+}
+
+// withLogFields adds fields to every later log entry of the request,
+// including those written by services through the request context.
+func withLogFields(c *gin.Context, fields logrus.Fields) {
+	c.Request = c.Request.WithContext(logging.WithFields(c.Request.Context(), fields))
+}
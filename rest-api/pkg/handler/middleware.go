@@ -2,14 +2,21 @@ package handler
 
 import (
 	"github.com/gin-gonic/gin"
+	"akhmet.com/rest-api"
 	"net/http"
+	"strconv"
 	"strings"
 	"errors"
+	"github.com/sirupsen/logrus"
 )
 
 const (
 	authorizationHeader = "Authorization"
+	workspaceHeader = "X-Workspace-Id"
 	userCtx = "userId"
+	scopesCtx = "scopes"
+	roleCtx = "role"
+	workspaceCtx = "workspaceId"
 )
 
 func (h *Handler) userIdentity(c *gin.Context) {
@@ -25,13 +32,123 @@ func (h *Handler) userIdentity(c *gin.Context) {
 		return
 	}
 
-	userId, err := h.services.Authorization.ParseToken(headerParts[1])
+	if h.services.ApiKey.IsApiKey(headerParts[1]) {
+		h.apiKeyIdentity(c, headerParts[1])
+		return
+	}
+
+	principal, err := h.services.Authorization.ParseToken(c.Request.Context(), headerParts[1])
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	c.Set(userCtx, principal.UserId)
+	c.Set(scopesCtx, principal.Scopes)
+	c.Set(roleCtx, principal.Role)
+	withLogFields(c, logrus.Fields{"user_id": principal.UserId})
+}
+
+func (h *Handler) apiKeyIdentity(c *gin.Context, plain string) {
+	key, err := h.services.ApiKey.ParseKey(c.Request.Context(), plain)
 	if err != nil {
 		newErrorResponse(c, http.StatusUnauthorized, err.Error())
 		return
 	}
 
-	c.Set(userCtx, userId)
+	c.Set(userCtx, key.UserId)
+	c.Set(scopesCtx, key.Scopes)
+	withLogFields(c, logrus.Fields{"user_id": key.UserId, "api_key_id": key.Id})
+}
+
+// requireScopes declares the scopes a route needs. Requests whose token
+// lacks any of them are rejected with 403 and the list of missing scopes.
+func (h *Handler) requireScopes(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted := getScopes(c)
+
+		missing := granted.Missing(scopes...)
+		if len(missing) == 0 {
+			return
+		}
+
+		message := "missing scope: " + strings.Join(missing, ", ")
+		logError(c, http.StatusForbidden, message)
+		c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+strings.Join(scopes, " ")+`"`)
+		c.AbortWithStatusJSON(http.StatusForbidden, insufficientScopeResponse{
+			Message:       message,
+			MissingScopes: missing,
+		})
+	}
+}
+
+// requireRole checks the role ParseToken read from the database, not the one
+// in the token claims, so a demoted admin loses access at once. Api keys
+// carry no role and are therefore always rejected.
+func (h *Handler) requireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(roleCtx) != role {
+			newErrorResponse(c, http.StatusForbidden, "role "+role+" required")
+		}
+	}
+}
+
+func getScopes(c *gin.Context) todo.Scopes {
+	scopes, _ := c.Get(scopesCtx)
+	granted, _ := scopes.(todo.Scopes)
+	return granted
+}
+
+// workspaceIdentity selects the workspace the request operates in, taken
+// from the X-Workspace-Id header and falling back to the personal workspace.
+func (h *Handler) workspaceIdentity(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	header := c.GetHeader(workspaceHeader)
+	if header == "" {
+		workspace, err := h.services.Workspace.GetPersonal(c.Request.Context(), userId)
+		if err != nil {
+			newErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		c.Set(workspaceCtx, workspace.Id)
+		withLogFields(c, logrus.Fields{"workspace_id": workspace.Id})
+		return
+	}
+
+	workspaceId, err := strconv.Atoi(header)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid workspace header")
+		return
+	}
+
+	if _, err := h.services.Workspace.GetById(c.Request.Context(), userId, workspaceId); err != nil {
+		newErrorResponse(c, http.StatusForbidden, "not a member of the workspace")
+		return
+	}
+
+	c.Set(workspaceCtx, workspaceId)
+	withLogFields(c, logrus.Fields{"workspace_id": workspaceId})
+}
+
+func getWorkspaceId(c *gin.Context) (int, error) {
+	id, ok := c.Get(workspaceCtx)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError, "workspace id not found")
+		return 0, errors.New("workspace id not found")
+	}
+
+	idInt, ok := id.(int)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError, "workspace id is of invalid type")
+		return 0, errors.New("workspace id not found")
+	}
+
+	return idInt, nil
 }
 
 func getUserId(c *gin.Context) (int, error) {
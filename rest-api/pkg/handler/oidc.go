@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"akhmet.com/rest-api/pkg/service"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	oidcStateCookie     = "oidc_login"
+	oidcStateCookiePath = "/auth/oidc"
+	oidcStateCookieTTL  = 10 * 60
+)
+
+func (h *Handler) oidcLogin(c *gin.Context) {
+	redirectURL, loginState, err := h.services.OIDC.LoginURL()
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, loginState, oidcStateCookieTTL, oidcStateCookiePath, "", c.Request.TLS != nil, true)
+	c.Redirect(http.StatusFound, redirectURL)
+}
+
+type oidcLinkResponse struct {
+	RedirectURL string `json:"redirect_url"`
+}
+
+// oidcLink starts linking a provider identity to the signed-in account. It
+// is called with a bearer token, so the browser is sent to the provider by
+// the client instead of with a redirect.
+func (h *Handler) oidcLink(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	redirectURL, loginState, err := h.services.OIDC.LinkURL(userId)
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, loginState, oidcStateCookieTTL, oidcStateCookiePath, "", c.Request.TLS != nil, true)
+	respond(c, http.StatusOK, oidcLinkResponse{RedirectURL: redirectURL})
+}
+
+func (h *Handler) oidcCallback(c *gin.Context) {
+	if providerError := c.Query("error"); providerError != "" {
+		newErrorResponse(c, http.StatusUnauthorized, providerError+": "+c.Query("error_description"))
+		return
+	}
+
+	loginState, err := c.Cookie(oidcStateCookie)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "oidc login state cookie not found")
+		return
+	}
+	c.SetCookie(oidcStateCookie, "", -1, oidcStateCookiePath, "", c.Request.TLS != nil, true)
+
+	userId, err := h.services.OIDC.Callback(c.Request.Context(), c.Query("code"), c.Query("state"), loginState)
+	if err != nil {
+		if errors.Is(err, service.ErrIdentityLinked) {
+			newErrorResponse(c, http.StatusConflict, err.Error())
+			return
+		}
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	user, err := h.services.Authorization.GetUserById(c.Request.Context(), userId)
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if user.Disabled {
+		newErrorResponse(c, http.StatusForbidden, service.ErrUserDisabled.Error())
+		return
+	}
+
+	h.issueToken(c, user)
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/service"
+)
+
+type fakeOIDC struct {
+	service.OIDC
+	userId int
+}
+
+func (f fakeOIDC) Callback(ctx context.Context, code, state, loginState string) (int, error) {
+	return f.userId, nil
+}
+
+type fakeAuthorization struct {
+	service.Authorization
+	user todo.User
+}
+
+func (f fakeAuthorization) GetUserById(ctx context.Context, userId int) (todo.User, error) {
+	return f.user, nil
+}
+
+func (f fakeAuthorization) GenerateToken(ctx context.Context, userId int, scopes todo.Scopes) (string, error) {
+	return "access-token", nil
+}
+
+type fakeTwoFactor struct {
+	service.TwoFactor
+}
+
+func (fakeTwoFactor) GenerateChallengeToken(userId int) (string, error) {
+	return "challenge-token", nil
+}
+
+func oidcCallbackResponse(t *testing.T, user todo.User) (int, signInResponse) {
+	h := &Handler{services: &service.Service{
+		OIDC:          fakeOIDC{userId: user.Id},
+		Authorization: fakeAuthorization{user: user},
+		TwoFactor:     fakeTwoFactor{},
+	}}
+
+	router := newTestRouter()
+	router.GET("/auth/oidc/callback", h.oidcCallback)
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?code=c&state=s", nil)
+	req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: "login-state"})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response signInResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w.Code, response
+}
+
+func TestOIDCCallbackRequiresTwoFactor(t *testing.T) {
+	code, response := oidcCallbackResponse(t, todo.User{Id: 1, TOTPEnabled: true})
+
+	if code != http.StatusOK || !response.TwoFactorRequired || response.ChallengeToken != "challenge-token" || response.Token != "" {
+		t.Fatalf("got %d %+v, want a two-factor challenge", code, response)
+	}
+}
+
+func TestOIDCCallbackIssuesToken(t *testing.T) {
+	code, response := oidcCallbackResponse(t, todo.User{Id: 1})
+
+	if code != http.StatusOK || response.Token != "access-token" || response.TwoFactorRequired {
+		t.Fatalf("got %d %+v, want an access token", code, response)
+	}
+}
+
+func TestOIDCCallbackRejectsDisabledUser(t *testing.T) {
+	code, _ := oidcCallbackResponse(t, todo.User{Id: 1, Disabled: true})
+
+	if code != http.StatusForbidden {
+		t.Fatalf("got %d, want %d", code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"akhmet.com/rest-api/pkg/jsonpatch"
+	"akhmet.com/rest-api/pkg/service"
+	"akhmet.com/rest-api/pkg/validate"
+	"github.com/gin-gonic/gin"
+)
+
+var acceptPatch = strings.Join([]string{jsonpatch.MergePatchType, jsonpatch.JSONPatchType}, ", ")
+
+// jsonPatchOperation documents a single RFC 6902 operation.
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// decodePatch reads a merge patch or json patch body depending on the
+// Content-Type of the request.
+func decodePatch(c *gin.Context) (jsonpatch.Patch, error) {
+	contentType := c.ContentType()
+	if contentType != jsonpatch.MergePatchType && contentType != jsonpatch.JSONPatchType {
+		c.Header("Accept-Patch", acceptPatch)
+		newErrorResponse(c, http.StatusUnsupportedMediaType, "content type must be one of "+acceptPatch)
+		return nil, errors.New("unsupported patch type")
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return nil, err
+	}
+
+	patch, err := jsonpatch.Decode(contentType, body)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return nil, err
+	}
+
+	return patch, nil
+}
+
+func newPatchErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		newErrorResponse(c, http.StatusNotFound, "not found")
+	case errors.Is(err, jsonpatch.ErrConflict):
+		newErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.As(err, new(validate.Errors)):
+		newValidationErrorResponse(c, err)
+	case errors.Is(err, service.ErrInvalidPatchResult):
+		newErrorResponse(c, http.StatusUnprocessableEntity, err.Error())
+	default:
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// rateLimitIdle is how long a client keeps its bucket without sending
+// requests before it is forgotten.
+const rateLimitIdle = 5 * time.Minute
+
+// RateLimit is how many requests per second a client address may send, with
+// bursts of up to Burst requests. A zero RequestsPerSecond turns limiting off.
+type RateLimit struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+// WithRateLimit sets the rate limit the handler starts with.
+func WithRateLimit(limit RateLimit) Option {
+	return func(h *Handler) {
+		h.limiter.set(limit)
+	}
+}
+
+// SetRateLimit replaces the rate limit of a running handler. Clients start
+// over with a full bucket.
+func (h *Handler) SetRateLimit(limit RateLimit) {
+	h.limiter.set(limit)
+}
+
+// rateLimiter keeps a token bucket per client address.
+type rateLimiter struct {
+	mu        sync.Mutex
+	limit     RateLimit
+	clients   map[string]*rateLimitClient
+	lastSweep time.Time
+}
+
+type rateLimitClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{clients: make(map[string]*rateLimitClient), lastSweep: time.Now()}
+}
+
+func (l *rateLimiter) set(limit RateLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limit = limit
+	l.clients = make(map[string]*rateLimitClient)
+}
+
+// allow takes a token from the bucket of key. When the bucket is empty it
+// returns how long the client should wait before trying again.
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limit.RequestsPerSecond <= 0 {
+		return true, 0
+	}
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) > rateLimitIdle {
+		for k, client := range l.clients {
+			if now.Sub(client.lastSeen) > rateLimitIdle {
+				delete(l.clients, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	client, ok := l.clients[key]
+	if !ok {
+		burst := l.limit.Burst
+		if burst < 1 {
+			burst = 1
+		}
+		client = &rateLimitClient{limiter: rate.NewLimiter(rate.Limit(l.limit.RequestsPerSecond), burst)}
+		l.clients[key] = client
+	}
+	client.lastSeen = now
+
+	if client.limiter.AllowN(now, 1) {
+		return true, 0
+	}
+
+	return false, time.Duration(float64(time.Second) / l.limit.RequestsPerSecond)
+}
+
+// rateLimit rejects requests with 429 once the client address has used up
+// its bucket, telling it in Retry-After when to try again.
+func (h *Handler) rateLimit(c *gin.Context) {
+	ok, retryAfter := h.limiter.allow(c.ClientIP())
+	if ok {
+		return
+	}
+
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	newErrorResponse(c, http.StatusTooManyRequests, "rate limit exceeded")
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimit(t *testing.T) {
+	h := NewHandler(nil, WithRateLimit(RateLimit{RequestsPerSecond: 1, Burst: 2}))
+	router := newTestRouter()
+	router.GET("/", h.rateLimit, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	get := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := get("192.0.2.1:1000"); w.Code != http.StatusOK {
+			t.Fatalf("request %d within burst: status %d", i+1, w.Code)
+		}
+	}
+
+	w := get("192.0.2.1:1001")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("request over burst: status %d, want 429", w.Code)
+	}
+	if retryAfter := w.Header().Get("Retry-After"); retryAfter != "1" {
+		t.Errorf("Retry-After = %q, want 1", retryAfter)
+	}
+
+	if w := get("192.0.2.2:1000"); w.Code != http.StatusOK {
+		t.Errorf("other client: status %d, want 200", w.Code)
+	}
+
+	h.SetRateLimit(RateLimit{})
+	for i := 0; i < 5; i++ {
+		if w := get("192.0.2.1:1000"); w.Code != http.StatusOK {
+			t.Fatalf("limiting off: status %d", w.Code)
+		}
+	}
+}
+
+func TestSignUpFeature(t *testing.T) {
+	h, router := newDocsTestHandler(WithFeatures(Features{SignUp: false}))
+
+	signUp := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/auth/sign-up", strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := signUp(); w.Code != http.StatusForbidden {
+		t.Errorf("sign-up off: status %d, want 403", w.Code)
+	}
+
+	h.SetFeatures(Features{SignUp: true})
+	if w := signUp(); w.Code == http.StatusForbidden {
+		t.Errorf("sign-up on: status %d", w.Code)
+	}
+}
@@ -1,19 +1,97 @@
 package handler
 
 import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"akhmet.com/rest-api/pkg/render"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type errorResponse struct {
 	Message string `json:"message"`
 }
 
+type insufficientScopeResponse struct {
+	Message       string   `json:"message"`
+	MissingScopes []string `json:"missing_scopes"`
+}
+
 type statusResponse struct {
 	Status string `json:"status"`
 }
 
+// dataResponse is the v2 envelope around every successful response body.
+type dataResponse struct {
+	Data interface{} `json:"data"`
+}
+
+// enveloped is implemented by v1 responses that already wrap their payload
+// in a data field, so v2 does not wrap it twice.
+type enveloped interface {
+	payload() interface{}
+}
+
+// collectionTypes are the media types collection endpoints negotiate
+// between with the Accept header, JSON being the default.
+var collectionTypes = []string{render.JSONType, render.CSVType, render.MsgPackType}
+
+// respond renders body as is for v1 and wrapped in a data envelope for v2.
+func respond(c *gin.Context, statusCode int, body interface{}) {
+	c.JSON(statusCode, versioned(c, body))
+}
+
+// respondCollection renders body like respond, or as CSV or MessagePack
+// when the Accept header prefers them. CSV has no envelope and carries
+// only the rows.
+func respondCollection(c *gin.Context, statusCode int, body interface{}) {
+	c.Writer.Header().Add("Vary", "Accept")
+
+	switch render.Negotiate(c.GetHeader("Accept"), collectionTypes...) {
+	case render.JSONType:
+		respond(c, statusCode, body)
+	case render.CSVType:
+		if e, ok := body.(enveloped); ok {
+			body = e.payload()
+		}
+		c.Render(statusCode, render.CSV{Data: body})
+	case render.MsgPackType:
+		c.Render(statusCode, render.MsgPack{Data: versioned(c, body)})
+	default:
+		newErrorResponse(c, http.StatusNotAcceptable, "acceptable types are "+strings.Join(collectionTypes, ", "))
+	}
+}
+
+// versioned returns the shape of body for the requested api version.
+func versioned(c *gin.Context, body interface{}) interface{} {
+	if c.GetInt(apiVersionCtx) < 2 {
+		return body
+	}
+
+	if e, ok := body.(enveloped); ok {
+		body = e.payload()
+	}
+
+	return dataResponse{Data: body}
+}
+
 func newErrorResponse(c *gin.Context, statusCode int, message string) {
-	logrus.Error(message)
+	logError(c, statusCode, message)
 	c.AbortWithStatusJSON(statusCode, errorResponse{message})
 }
+
+// logError logs why a request failed, as an error when the server is at
+// fault and as a warning when the client is. Server errors are recorded
+// on the request span as well.
+func logError(c *gin.Context, statusCode int, message string) {
+	level := logrus.WarnLevel
+	if statusCode >= http.StatusInternalServerError {
+		level = logrus.ErrorLevel
+		trace.SpanFromContext(c.Request.Context()).RecordError(errors.New(message))
+	}
+
+	requestLogger(c).WithField("status", statusCode).Log(level, message)
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testRow struct {
+	Title string `json:"title"`
+}
+
+type testRows struct {
+	Data []testRow `json:"data"`
+}
+
+func (r testRows) payload() interface{} {
+	return r.Data
+}
+
+func TestRespondCollectionNegotiates(t *testing.T) {
+	router := newTestRouter()
+	router.GET("/", func(c *gin.Context) {
+		respondCollection(c, http.StatusOK, testRows{Data: []testRow{{Title: "=1+1"}}})
+	})
+
+	tests := []struct {
+		accept      string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"", http.StatusOK, "application/json; charset=utf-8", `{"data":[{"title":"=1+1"}]}`},
+		{"text/csv", http.StatusOK, "text/csv; charset=utf-8", "title\n'=1+1\n"},
+		{"text/csv;q=0.4, application/msgpack;q=0.6", http.StatusOK, "application/msgpack", ""},
+		{"application/xml", http.StatusNotAcceptable, "application/json; charset=utf-8", ""},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", test.accept)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != test.status {
+			t.Errorf("Accept %q: status %d, want %d", test.accept, w.Code, test.status)
+		}
+		if got := w.Header().Get("Content-Type"); got != test.contentType {
+			t.Errorf("Accept %q: Content-Type %q, want %q", test.accept, got, test.contentType)
+		}
+		if got := w.Header().Get("Vary"); got != "Accept" {
+			t.Errorf("Accept %q: Vary %q, want Accept", test.accept, got)
+		}
+		if test.body != "" && w.Body.String() != test.body {
+			t.Errorf("Accept %q: body %q, want %q", test.accept, w.Body.String(), test.body)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"net/http"
+	"path"
+
+	"akhmet.com/rest-api/pkg/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+// routeGroup registers gin routes together with their documentation, so
+// the spec is generated from what InitRoutes actually routes. Scopes given
+// to a group or a route are enforced with requireScopes and documented from
+// the same list, which keeps the two from disagreeing.
+type routeGroup struct {
+	h          *Handler
+	group      *gin.RouterGroup
+	operations *[]openapi.Operation
+
+	authenticated bool
+	rateLimited   bool
+	scopes        []string
+	// version is the API version of the group's routes, zero outside /api.
+	version int
+}
+
+func (h *Handler) newRouteGroup(router *gin.Engine) routeGroup {
+	return routeGroup{h: h, group: &router.RouterGroup, operations: &[]openapi.Operation{}}
+}
+
+func (r routeGroup) Group(relativePath string, handlers ...gin.HandlerFunc) routeGroup {
+	r.group = r.group.Group(relativePath, handlers...)
+	return r
+}
+
+// Authenticated requires a token or api key for the routes of the group.
+func (r routeGroup) Authenticated() routeGroup {
+	r = r.Group("", r.h.userIdentity)
+	r.authenticated = true
+	return r
+}
+
+// RateLimited applies the per-client rate limit to the routes of the group.
+func (r routeGroup) RateLimited() routeGroup {
+	r = r.Group("", r.h.rateLimit)
+	r.rateLimited = true
+	return r
+}
+
+// RequireScopes requires scopes for every route of the group.
+func (r routeGroup) RequireScopes(scopes ...string) routeGroup {
+	r = r.Group("", r.h.requireScopes(scopes...))
+	r.scopes = append(append([]string(nil), r.scopes...), scopes...)
+	return r
+}
+
+// Version tags the routes of the group with an API version.
+func (r routeGroup) Version(version int) routeGroup {
+	r = r.Group("", r.h.apiVersion(version))
+	r.version = version
+	return r
+}
+
+// Handle registers op.Method and op.Path, relative to the group, and
+// documents it. op.Scopes are the scopes of this route on top of the
+// group's.
+func (r routeGroup) Handle(op openapi.Operation, handlers ...gin.HandlerFunc) {
+	if len(op.Scopes) > 0 {
+		handlers = append([]gin.HandlerFunc{r.h.requireScopes(op.Scopes...)}, handlers...)
+	}
+	r.group.Handle(op.Method, op.Path, handlers...)
+
+	op.Path = joinPaths(r.group.BasePath(), op.Path)
+	op.Scopes = append(append([]string(nil), r.scopes...), op.Scopes...)
+	op.Public = !r.authenticated
+	op.RateLimited = r.rateLimited
+	if r.version != 0 {
+		op = versionOperation(op, r.version)
+	}
+
+	*r.operations = append(*r.operations, op)
+}
+
+func (r routeGroup) GET(op openapi.Operation, handlers ...gin.HandlerFunc) {
+	op.Method = http.MethodGet
+	r.Handle(op, handlers...)
+}
+
+func (r routeGroup) POST(op openapi.Operation, handlers ...gin.HandlerFunc) {
+	op.Method = http.MethodPost
+	r.Handle(op, handlers...)
+}
+
+func (r routeGroup) PUT(op openapi.Operation, handlers ...gin.HandlerFunc) {
+	op.Method = http.MethodPut
+	r.Handle(op, handlers...)
+}
+
+func (r routeGroup) PATCH(op openapi.Operation, handlers ...gin.HandlerFunc) {
+	op.Method = http.MethodPatch
+	r.Handle(op, handlers...)
+}
+
+func (r routeGroup) DELETE(op openapi.Operation, handlers ...gin.HandlerFunc) {
+	op.Method = http.MethodDelete
+	r.Handle(op, handlers...)
+}
+
+// joinPaths joins paths the way gin does for the routes it reports,
+// keeping a trailing slash of the relative path.
+func joinPaths(absolutePath, relativePath string) string {
+	if relativePath == "" {
+		return absolutePath
+	}
+
+	finalPath := path.Join(absolutePath, relativePath)
+	if relativePath[len(relativePath)-1] == '/' && finalPath[len(finalPath)-1] != '/' {
+		return finalPath + "/"
+	}
+
+	return finalPath
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"akhmet.com/rest-api/pkg/service"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) enrollTwoFactor(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	enrollment, err := h.services.TwoFactor.Enroll(c.Request.Context(), userId)
+	if err != nil {
+		if errors.Is(err, service.ErrTwoFactorEnabled) {
+			newErrorResponse(c, http.StatusConflict, err.Error())
+			return
+		}
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respond(c, http.StatusOK, enrollment)
+}
+
+type confirmTwoFactorInput struct {
+	Code string `json:"code" validate:"trim,required"`
+}
+
+func (h *Handler) confirmTwoFactor(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	var input confirmTwoFactorInput
+	if !bindJSON(c, &input) {
+		return
+	}
+
+	codes, err := h.services.TwoFactor.Confirm(c.Request.Context(), userId, input.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidTwoFactorCode):
+			newErrorResponse(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrTwoFactorEnabled), errors.Is(err, service.ErrTwoFactorNotEnrolled):
+			newErrorResponse(c, http.StatusConflict, err.Error())
+		default:
+			newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	respond(c, http.StatusOK, map[string]interface{}{
+		"recovery_codes": codes,
+	})
+}
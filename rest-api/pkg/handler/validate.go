@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"akhmet.com/rest-api/pkg/validate"
+	"github.com/gin-gonic/gin"
+)
+
+type validationErrorResponse struct {
+	Message string                `json:"message"`
+	Errors  []validate.FieldError `json:"errors"`
+}
+
+// bindJSON decodes the request body into input and validates it. Malformed
+// JSON is answered with 400 and invalid fields with 422; in both cases it
+// returns false and the handler must stop.
+func bindJSON(c *gin.Context, input interface{}) bool {
+	if err := c.ShouldBindJSON(input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		newValidationErrorResponse(c, err)
+		return false
+	}
+
+	return true
+}
+
+// newValidationErrorResponse reports every failing field of err, which
+// must wrap validate.Errors.
+func newValidationErrorResponse(c *gin.Context, err error) {
+	var errs validate.Errors
+	if !errors.As(err, &errs) {
+		newErrorResponse(c, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	logError(c, http.StatusUnprocessableEntity, err.Error())
+	c.AbortWithStatusJSON(http.StatusUnprocessableEntity, validationErrorResponse{
+		Message: "validation failed",
+		Errors:  errs,
+	})
+}
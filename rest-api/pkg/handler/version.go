@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"net/http"
+	"reflect"
+	"time"
+
+	"akhmet.com/rest-api/pkg/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	apiVersionCtx = "apiVersion"
+	apiPrefix     = "/api"
+	latestVersion = 2
+)
+
+// apiVersions maps route prefixes to the version they serve. The
+// unversioned prefix predates versioning and behaves like v1.
+var apiVersions = []struct {
+	prefix  string
+	version int
+}{
+	{apiPrefix, 1},
+	{apiPrefix + "/v1", 1},
+	{apiPrefix + "/v2", 2},
+}
+
+// WithV1Deprecation announces deprecatedAt and sunset in the Deprecation
+// and Sunset headers of v1 responses. A zero time leaves its header out.
+func WithV1Deprecation(deprecatedAt, sunset time.Time) Option {
+	return func(h *Handler) {
+		h.v1DeprecatedAt = deprecatedAt
+		h.v1Sunset = sunset
+	}
+}
+
+// apiVersion tags the request with the API version its route belongs to.
+// Older versions point to their successor and announce the configured
+// deprecation and sunset dates.
+func (h *Handler) apiVersion(version int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(apiVersionCtx, version)
+
+		if version < latestVersion {
+			if !h.v1DeprecatedAt.IsZero() {
+				c.Header("Deprecation", h.v1DeprecatedAt.UTC().Format(http.TimeFormat))
+			}
+			if !h.v1Sunset.IsZero() {
+				c.Header("Sunset", h.v1Sunset.UTC().Format(http.TimeFormat))
+			}
+			c.Header("Link", `</api/v2>; rel="successor-version"`)
+		}
+	}
+}
+
+// versionOperation documents an /api operation for one version. v2
+// responses are described wrapped in their data envelope.
+func versionOperation(op openapi.Operation, version int) openapi.Operation {
+	if version < latestVersion {
+		op.Deprecated = true
+	} else if op.Response != nil {
+		op.Response = envelopeOf(op.Response)
+	}
+
+	return op
+}
+
+// envelopeOf returns a zero value of struct{ Data T `json:"data"` } for the
+// payload type of response.
+func envelopeOf(response interface{}) interface{} {
+	if e, ok := response.(enveloped); ok {
+		response = e.payload()
+	}
+
+	t := reflect.StructOf([]reflect.StructField{{
+		Name: "Data",
+		Type: reflect.TypeOf(response),
+		Tag:  `json:"data"`,
+	}})
+
+	return reflect.New(t).Elem().Interface()
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/service"
+)
+
+type exchangeTokens struct {
+	scopeTokens
+}
+
+func (exchangeTokens) GenerateToken(ctx context.Context, userId int, scopes todo.Scopes) (string, error) {
+	return "exchanged", nil
+}
+
+func TestDeprecationHeaders(t *testing.T) {
+	deprecatedAt := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2030, time.July, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, tt := range []struct {
+		name                string
+		opts                []Option
+		path                string
+		deprecation, sunset string
+		link                bool
+	}{
+		{"v1 unset", nil, "/api/v1/lists/", "", "", true},
+		{"v1 configured", []Option{WithV1Deprecation(deprecatedAt, sunset)}, "/api/v1/lists/", "Tue, 01 Jan 2030 00:00:00 GMT", "Mon, 01 Jul 2030 00:00:00 GMT", true},
+		{"unversioned configured", []Option{WithV1Deprecation(deprecatedAt, sunset)}, "/api/lists/", "Tue, 01 Jan 2030 00:00:00 GMT", "Mon, 01 Jul 2030 00:00:00 GMT", true},
+		{"v2 configured", []Option{WithV1Deprecation(deprecatedAt, sunset)}, "/api/v2/lists/", "", "", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, router := newDocsTestHandler(tt.opts...)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if got := w.Header().Get("Deprecation"); got != tt.deprecation {
+				t.Errorf("Deprecation %q, want %q", got, tt.deprecation)
+			}
+			if got := w.Header().Get("Sunset"); got != tt.sunset {
+				t.Errorf("Sunset %q, want %q", got, tt.sunset)
+			}
+			if got := w.Header().Get("Link") != ""; got != tt.link {
+				t.Errorf("Link sent: %v, want %v", got, tt.link)
+			}
+		})
+	}
+}
+
+func TestResponsesAreEnvelopedOnlyInV2(t *testing.T) {
+	h := NewHandler(&service.Service{Authorization: exchangeTokens{}, ApiKey: noApiKeys{}})
+	router := h.InitRoutes()
+
+	for path, want := range map[string]string{
+		"/api/v1/tokens": `{"token":"exchanged"}`,
+		"/api/v2/tokens": `{"data":{"token":"exchanged"}}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"scopes":["lists:read"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(authorizationHeader, "Bearer scopes:account+lists:read")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if got := strings.TrimSpace(w.Body.String()); w.Code != http.StatusOK || got != want {
+			t.Errorf("%s: got %d %s, want %s", path, w.Code, got, want)
+		}
+	}
+}
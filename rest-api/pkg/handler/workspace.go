@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/service"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) createWorkspace(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	var input todo.Workspace
+	if !bindJSON(c, &input) {
+		return
+	}
+
+	id, err := h.services.Workspace.Create(c.Request.Context(), userId, input)
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respond(c, http.StatusOK, map[string]interface{}{
+		"id": id,
+	})
+}
+
+type getAllWorkspacesResponse struct {
+	Data []todo.Workspace `json:"data"`
+}
+
+func (r getAllWorkspacesResponse) payload() interface{} { return r.Data }
+
+func (h *Handler) getAllWorkspaces(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	workspaces, err := h.services.Workspace.GetAll(c.Request.Context(), userId)
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondCollection(c, http.StatusOK, getAllWorkspacesResponse{
+		Data: workspaces,
+	})
+}
+
+type getWorkspaceMembersResponse struct {
+	Data []todo.WorkspaceMember `json:"data"`
+}
+
+func (r getWorkspaceMembersResponse) payload() interface{} { return r.Data }
+
+func (h *Handler) getWorkspaceMembers(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	members, err := h.services.Workspace.GetMembers(c.Request.Context(), userId, id)
+	if err != nil {
+		newWorkspaceErrorResponse(c, err)
+		return
+	}
+
+	respondCollection(c, http.StatusOK, getWorkspaceMembersResponse{
+		Data: members,
+	})
+}
+
+func (h *Handler) addWorkspaceMember(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	var input todo.AddWorkspaceMemberInput
+	if !bindJSON(c, &input) {
+		return
+	}
+
+	if err := h.services.Workspace.AddMember(c.Request.Context(), userId, id, input); err != nil {
+		newWorkspaceErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, statusResponse{
+		Status: "ok",
+	})
+}
+
+func (h *Handler) removeWorkspaceMember(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	memberId, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid user id param")
+		return
+	}
+
+	if err := h.services.Workspace.RemoveMember(c.Request.Context(), userId, id, memberId); err != nil {
+		newWorkspaceErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, statusResponse{
+		Status: "ok",
+	})
+}
+
+func newWorkspaceErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		newErrorResponse(c, http.StatusNotFound, "not found")
+	case errors.Is(err, service.ErrWorkspaceOwnerRequired):
+		newErrorResponse(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrLastWorkspaceOwner):
+		newErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrInvalidWorkspaceRole), errors.Is(err, service.ErrPersonalWorkspace):
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+	default:
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+	}
+}
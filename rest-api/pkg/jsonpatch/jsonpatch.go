@@ -0,0 +1,56 @@
+// Package jsonpatch applies JSON Merge Patch (RFC 7396) and JSON Patch
+// (RFC 6902) documents to JSON values.
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+var (
+	// ErrInvalidPatch is returned when the patch document is malformed.
+	ErrInvalidPatch = errors.New("invalid patch document")
+	// ErrConflict is returned when a well formed patch cannot be applied to
+	// the target, e.g. a path does not exist or a test operation fails.
+	ErrConflict = errors.New("patch cannot be applied")
+)
+
+// Patch transforms a JSON document.
+type Patch interface {
+	Apply(doc []byte) ([]byte, error)
+}
+
+// Decode parses body as a patch of the given media type.
+func Decode(contentType string, body []byte) (Patch, error) {
+	switch contentType {
+	case MergePatchType:
+		return DecodeMergePatch(body)
+	case JSONPatchType:
+		return DecodeJSONPatch(body)
+	default:
+		return nil, fmt.Errorf("%w: unsupported media type %q", ErrInvalidPatch, contentType)
+	}
+}
+
+func decodeValue(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	if decoder.More() {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+
+	return value, nil
+}
@@ -0,0 +1,202 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// assertJSON compares JSON documents by value, ignoring member order and
+// formatting.
+func assertJSON(t *testing.T, got []byte, want string) {
+	t.Helper()
+
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("result %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("want %s: %v", want, err)
+	}
+
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{"add member", `{"a":1}`, `[{"op":"add","path":"/b","value":2}]`, `{"a":1,"b":2}`},
+		{"add replaces member", `{"a":1}`, `[{"op":"add","path":"/a","value":2}]`, `{"a":2}`},
+		{"add into array", `{"a":[1,3]}`, `[{"op":"add","path":"/a/1","value":2}]`, `{"a":[1,2,3]}`},
+		{"add at array length", `{"a":[1]}`, `[{"op":"add","path":"/a/1","value":2}]`, `{"a":[1,2]}`},
+		{"add with dash appends", `{"a":[1]}`, `[{"op":"add","path":"/a/-","value":2}]`, `{"a":[1,2]}`},
+		{"add replaces root", `{"a":1}`, `[{"op":"add","path":"","value":[1]}]`, `[1]`},
+		{"remove member", `{"a":1,"b":2}`, `[{"op":"remove","path":"/a"}]`, `{"b":2}`},
+		{"remove from array", `{"a":[1,2,3]}`, `[{"op":"remove","path":"/a/1"}]`, `{"a":[1,3]}`},
+		{"replace member", `{"a":{"b":1}}`, `[{"op":"replace","path":"/a/b","value":"x"}]`, `{"a":{"b":"x"}}`},
+		{"replace in array", `[1,2]`, `[{"op":"replace","path":"/0","value":0}]`, `[0,2]`},
+		{"move member", `{"a":{"b":1},"c":{}}`, `[{"op":"move","from":"/a/b","path":"/c/d"}]`, `{"a":{},"c":{"d":1}}`},
+		{"move in array", `[1,2,3]`, `[{"op":"move","from":"/0","path":"/-"}]`, `[2,3,1]`},
+		{"copy member", `{"a":{"b":[1]}}`, `[{"op":"copy","from":"/a","path":"/c"}]`, `{"a":{"b":[1]},"c":{"b":[1]}}`},
+		{"copy is deep", `{"a":[1]}`, `[{"op":"copy","from":"/a","path":"/b"},{"op":"add","path":"/b/-","value":2}]`, `{"a":[1],"b":[1,2]}`},
+		{"test scalar", `{"a":"x"}`, `[{"op":"test","path":"/a","value":"x"}]`, `{"a":"x"}`},
+		{"test number formatting", `{"a":1}`, `[{"op":"test","path":"/a","value":1.0}]`, `{"a":1}`},
+		{"test nested numbers", `{"a":{"b":[1,{"c":2.50}]}}`, `[{"op":"test","path":"/a","value":{"b":[1.0,{"c":2.5}]}}]`, `{"a":{"b":[1,{"c":2.5}]}}`},
+		{"test null", `{"a":null}`, `[{"op":"test","path":"/a","value":null}]`, `{"a":null}`},
+		{"tilde escapes", `{"a/b":1,"c~d":2}`, `[{"op":"remove","path":"/a~1b"},{"op":"replace","path":"/c~0d","value":3}]`, `{"c~d":3}`},
+		{"escapes are decoded once", `{"~1":1}`, `[{"op":"remove","path":"/~01"}]`, `{}`},
+		{"operations apply in order", `{}`, `[{"op":"add","path":"/a","value":[]},{"op":"add","path":"/a/-","value":1},{"op":"test","path":"/a/0","value":1}]`, `{"a":[1]}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patch, err := DecodeJSONPatch([]byte(test.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := patch.Apply([]byte(test.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertJSON(t, got, test.want)
+		})
+	}
+}
+
+func TestJSONPatchConflict(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+	}{
+		{"remove missing member", `{}`, `[{"op":"remove","path":"/a"}]`},
+		{"replace missing member", `{}`, `[{"op":"replace","path":"/a","value":1}]`},
+		{"add to missing parent", `{}`, `[{"op":"add","path":"/a/b","value":1}]`},
+		{"add past array end", `[1]`, `[{"op":"add","path":"/2","value":2}]`},
+		{"remove at array length", `[1]`, `[{"op":"remove","path":"/1"}]`},
+		{"remove with dash", `[1]`, `[{"op":"remove","path":"/-"}]`},
+		{"negative index", `[1]`, `[{"op":"replace","path":"/-1","value":2}]`},
+		{"leading zero index", `[1,2]`, `[{"op":"remove","path":"/01"}]`},
+		{"move into itself", `{"a":{}}`, `[{"op":"move","from":"/a","path":"/a/b"}]`},
+		{"copy missing member", `{}`, `[{"op":"copy","from":"/a","path":"/b"}]`},
+		{"test mismatch", `{"a":1}`, `[{"op":"test","path":"/a","value":2}]`},
+		{"test nested mismatch", `{"a":[1,2]}`, `[{"op":"test","path":"/a","value":[1,3]}]`},
+		{"test extra member", `{"a":{"b":1}}`, `[{"op":"test","path":"/a","value":{"b":1,"c":2}}]`},
+		{"test type mismatch", `{"a":"1"}`, `[{"op":"test","path":"/a","value":1}]`},
+		{"pointer without slash", `{"a":1}`, `[{"op":"remove","path":"a"}]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patch, err := DecodeJSONPatch([]byte(test.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := patch.Apply([]byte(test.doc)); !errors.Is(err, ErrConflict) {
+				t.Errorf("err = %v, want ErrConflict", err)
+			}
+		})
+	}
+}
+
+func TestJSONPatchFailureLeavesTargetUnchanged(t *testing.T) {
+	doc := []byte(`{"title":"a","done":false}`)
+	patch, err := DecodeJSONPatch([]byte(`[
+		{"op":"replace","path":"/title","value":"b"},
+		{"op":"remove","path":"/missing"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := patch.Apply(doc)
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("err = %v, want ErrConflict", err)
+	}
+	if got != nil {
+		t.Errorf("got %s, want no result", got)
+	}
+	if string(doc) != `{"title":"a","done":false}` {
+		t.Errorf("target changed to %s", doc)
+	}
+}
+
+func TestDecodeJSONPatchInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+	}{
+		{"not an array", `{"op":"add"}`},
+		{"unknown op", `[{"op":"merge","path":"/a"}]`},
+		{"missing path", `[{"op":"remove"}]`},
+		{"missing value", `[{"op":"add","path":"/a"}]`},
+		{"missing from", `[{"op":"move","path":"/a"}]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := DecodeJSONPatch([]byte(test.patch)); !errors.Is(err, ErrInvalidPatch) {
+				t.Errorf("err = %v, want ErrInvalidPatch", err)
+			}
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{"replace member", `{"title":"a","done":false}`, `{"title":"b"}`, `{"title":"b","done":false}`},
+		{"null clears description", `{"title":"a","description":"d"}`, `{"description":null}`, `{"title":"a"}`},
+		{"null on missing member", `{"title":"a"}`, `{"description":null}`, `{"title":"a"}`},
+		{"nested merge", `{"a":{"b":1,"c":2}}`, `{"a":{"b":null,"d":3}}`, `{"a":{"c":2,"d":3}}`},
+		{"arrays are replaced", `{"a":[1,2]}`, `{"a":[3]}`, `{"a":[3]}`},
+		{"object replaces scalar", `{"a":1}`, `{"a":{"b":null,"c":2}}`, `{"a":{"c":2}}`},
+		{"non-object patch replaces target", `{"a":1}`, `[1]`, `[1]`},
+		{"empty patch", `{"a":1}`, `{}`, `{"a":1}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patch, err := DecodeMergePatch([]byte(test.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := patch.Apply([]byte(test.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertJSON(t, got, test.want)
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	if _, err := Decode(MergePatchType, []byte(`{"a":1}`)); err != nil {
+		t.Errorf("merge patch: %v", err)
+	}
+	if _, err := Decode(JSONPatchType, []byte(`[]`)); err != nil {
+		t.Errorf("json patch: %v", err)
+	}
+	if _, err := Decode("application/json", []byte(`{}`)); !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("err = %v, want ErrInvalidPatch", err)
+	}
+	if _, err := Decode(MergePatchType, []byte(`{"a":1} {}`)); !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("trailing data: err = %v, want ErrInvalidPatch", err)
+	}
+}
@@ -0,0 +1,53 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MergePatch is an RFC 7396 merge patch. Object members set to null are
+// removed from the target, other members replace or recursively merge.
+type MergePatch struct {
+	value interface{}
+}
+
+func DecodeMergePatch(body []byte) (*MergePatch, error) {
+	value, err := decodeValue(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, err.Error())
+	}
+
+	return &MergePatch{value: value}, nil
+}
+
+func (p *MergePatch) Apply(doc []byte) ([]byte, error) {
+	target, err := decodeValue(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(mergeValue(target, p.value))
+}
+
+func mergeValue(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+
+		targetObject[name] = mergeValue(targetObject[name], value)
+	}
+
+	return targetObject
+}
@@ -0,0 +1,72 @@
+// Package logging carries a request-scoped logger in context.Context so
+// every layer logs with the request id and caller of the request it is
+// serving, and keeps secrets out of the log output.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader carries the request id between services. Incoming ids
+// are reused so one id follows a request through every hop.
+const RequestIDHeader = "X-Request-ID"
+
+const maxRequestIDLength = 128
+
+type loggerKey struct{}
+
+// WithLogger returns a copy of ctx carrying entry.
+func WithLogger(ctx context.Context, entry *logrus.Entry) context.Context {
+	return context.WithValue(ctx, loggerKey{}, entry)
+}
+
+// FromContext returns the logger of the request ctx belongs to, or the
+// standard logger outside of a request.
+func FromContext(ctx context.Context) *logrus.Entry {
+	if entry, ok := ctx.Value(loggerKey{}).(*logrus.Entry); ok {
+		return entry
+	}
+
+	return logrus.NewEntry(logrus.StandardLogger())
+}
+
+// WithFields adds fields to the logger carried by ctx.
+func WithFields(ctx context.Context, fields logrus.Fields) context.Context {
+	return WithLogger(ctx, FromContext(ctx).WithFields(fields))
+}
+
+// RequestID returns id when it is a usable request id and a new random id
+// otherwise, so clients cannot inject arbitrary text into the logs.
+func RequestID(id string) string {
+	if id != "" && len(id) <= maxRequestIDLength && strings.IndexFunc(id, invalidRequestIDRune) < 0 {
+		return id
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(buf)
+}
+
+func invalidRequestIDRune(r rune) bool {
+	return r <= ' ' || r > '~'
+}
+
+// TraceFields returns the trace and span ids of the span in ctx so logs
+// can be joined with the traces of the request.
+func TraceFields(ctx context.Context) logrus.Fields {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return nil
+	}
+
+	return logrus.Fields{"trace_id": spanContext.TraceID().String(), "span_id": spanContext.SpanID().String()}
+}
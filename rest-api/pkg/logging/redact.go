@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Redacted replaces secrets in log output.
+const Redacted = "[REDACTED]"
+
+// sensitiveFields are substrings of field and parameter names whose values
+// are never logged. Names are compared in lower case with dashes read as
+// underscores, so X-Api-Key matches api_key.
+var sensitiveFields = []string{"password", "secret", "token", "authorization", "cookie", "api_key", "apikey"}
+
+// sensitiveParams are query parameters that carry one-time secrets.
+var sensitiveParams = map[string]bool{"code": true, "state": true}
+
+var messagePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(bearer\s+)[^\s",]+`),
+	regexp.MustCompile(`(?i)("?[a-z_]*(?:password|secret|token)"?\s*[:=]\s*"?)[^\s",&]+`),
+	regexp.MustCompile(`(todo_[0-9a-f]+_)[0-9a-f]+`),
+}
+
+// RedactHook scrubs sensitive fields and credentials embedded in messages
+// from every entry before it is written.
+type RedactHook struct{}
+
+func (RedactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (RedactHook) Fire(entry *logrus.Entry) error {
+	for key := range entry.Data {
+		if isSensitive(key) {
+			entry.Data[key] = Redacted
+		}
+	}
+
+	entry.Message = RedactText(entry.Message)
+
+	return nil
+}
+
+// RedactText masks bearer tokens, api keys and password or token
+// assignments in free text.
+func RedactText(text string) string {
+	for _, pattern := range messagePatterns {
+		text = pattern.ReplaceAllString(text, "${1}"+Redacted)
+	}
+
+	return text
+}
+
+// RedactQuery masks the values of sensitive query parameters and keeps
+// the rest of the query as sent.
+func RedactQuery(rawQuery string) string {
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		rawKey := param
+		if j := strings.IndexByte(param, '='); j >= 0 {
+			rawKey = param[:j]
+		}
+
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			key = rawKey
+		}
+
+		if isSensitive(key) || sensitiveParams[strings.ToLower(key)] {
+			params[i] = rawKey + "=" + Redacted
+		}
+	}
+
+	return strings.Join(params, "&")
+}
+
+// isSensitive reports whether the value of name is a secret. Identifiers
+// such as api_key_id only reference a secret and are kept, so key usage
+// stays traceable in the logs.
+func isSensitive(name string) bool {
+	name = strings.ReplaceAll(strings.ToLower(name), "-", "_")
+	if strings.HasSuffix(name, "_id") {
+		return false
+	}
+
+	for _, sensitive := range sensitiveFields {
+		if strings.Contains(name, sensitive) {
+			return true
+		}
+	}
+
+	return false
+}
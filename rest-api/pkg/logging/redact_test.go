@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRedactHookFields(t *testing.T) {
+	tests := []struct {
+		field    string
+		redacted bool
+	}{
+		{"api_key", true},
+		{"x-api-key", true},
+		{"X-Api-Key", true},
+		{"apikey", true},
+		{"password", true},
+		{"refresh_token", true},
+		{"Authorization", true},
+		{"api_key_id", false},
+		{"user_id", false},
+		{"path", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.field, func(t *testing.T) {
+			entry := &logrus.Entry{Data: logrus.Fields{test.field: "value"}}
+			if err := (RedactHook{}).Fire(entry); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := entry.Data[test.field] == Redacted; got != test.redacted {
+				t.Errorf("%s redacted = %v, want %v", test.field, got, test.redacted)
+			}
+		})
+	}
+}
+
+func TestRedactQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"limit=10&offset=5", "limit=10&offset=5"},
+		{"api_key=todo_1_2&limit=10", "api_key=" + Redacted + "&limit=10"},
+		{"api_key_id=42", "api_key_id=42"},
+		{"code=123456&state=abc", "code=" + Redacted + "&state=" + Redacted},
+	}
+
+	for _, test := range tests {
+		if got := RedactQuery(test.query); got != test.want {
+			t.Errorf("RedactQuery(%q) = %q, want %q", test.query, got, test.want)
+		}
+	}
+}
+
+func TestRedactText(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Authorization: Bearer abc.def", "Authorization: Bearer " + Redacted},
+		{`{"password":"hunter2"}`, `{"password":"` + Redacted + `"}`},
+		{"key todo_0a1b_c0ffee", "key todo_0a1b_" + Redacted},
+	}
+
+	for _, test := range tests {
+		if got := RedactText(test.text); got != test.want {
+			t.Errorf("RedactText(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Operation documents one gin route. Request and Response hold zero values
+// of the Go types the handler binds and renders.
+type Operation struct {
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Tags        []string
+	Public      bool
+	Scopes      []string
+	Query       []Parameter
+	Headers     []Parameter
+	Request     interface{}
+	Response    interface{}
+	Errors      []int
+	Deprecated  bool
+	// RateLimited routes can be rejected with 429.
+	RateLimited bool
+
+	// RequestContent documents request bodies by media type for routes
+	// that accept something other than application/json.
+	RequestContent map[string]interface{}
+
+	// ResponseTypes lists media types besides application/json the
+	// response can be negotiated to. Binary types share the JSON schema;
+	// text types are documented as plain strings.
+	ResponseTypes []string
+}
+
+type Parameter struct {
+	Name        string
+	Description string
+	Type        string
+	Required    bool
+}
+
+const bearerScheme = "bearerAuth"
+
+// Build generates the document for the registered routes. It fails when a
+// route has no documentation or documentation refers to a route that does
+// not exist, so the spec cannot silently drift from the router.
+// Operations with a JSON request body document validationResponse as their
+// 422 response; every other error uses errorResponse.
+func Build(info Info, routes gin.RoutesInfo, operations []Operation, errorResponse, validationResponse interface{}) (*Document, error) {
+	registered := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		registered[route.Method+" "+route.Path] = true
+	}
+
+	documented := make(map[string]bool, len(operations))
+	var problems []string
+
+	for _, op := range operations {
+		key := op.Method + " " + op.Path
+		if documented[key] {
+			problems = append(problems, "documented twice: "+key)
+		}
+		documented[key] = true
+
+		if !registered[key] {
+			problems = append(problems, "documented but not routed: "+key)
+		}
+	}
+
+	for _, route := range routes {
+		key := route.Method + " " + route.Path
+		if !documented[key] {
+			problems = append(problems, "routed but not documented: "+key)
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, fmt.Errorf("openapi spec drift:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	s := newSchemas()
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			SecuritySchemes: map[string]SecurityScheme{
+				bearerScheme: {
+					Type:         "http",
+					Scheme:       "bearer",
+					BearerFormat: "JWT or api key",
+				},
+			},
+		},
+	}
+	errorSchema := s.of(errorResponse)
+	validationSchema := s.of(validationResponse)
+	tags := make(map[string]bool)
+
+	for _, op := range operations {
+		if !registered[op.Method+" "+op.Path] {
+			continue
+		}
+
+		path, pathParams := convertPath(op.Path)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = make(PathItem)
+			doc.Paths[path] = item
+		}
+
+		object := &OperationObject{
+			OperationId: operationId(op.Method, op.Path),
+			Summary:     op.Summary,
+			Description: op.Description,
+			Tags:        op.Tags,
+			Scopes:      op.Scopes,
+			Deprecated:  op.Deprecated,
+			Responses:   make(map[string]Response),
+		}
+
+		for _, name := range pathParams {
+			object.Parameters = append(object.Parameters, ParameterObject{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "integer"},
+			})
+		}
+		for _, p := range op.Query {
+			object.Parameters = append(object.Parameters, parameter(p, "query"))
+		}
+		for _, p := range op.Headers {
+			object.Parameters = append(object.Parameters, parameter(p, "header"))
+		}
+
+		if op.Request != nil {
+			object.RequestBody = &RequestBody{
+				Required: true,
+				Content:  jsonContent(s.of(op.Request)),
+			}
+		}
+		if len(op.RequestContent) > 0 {
+			content := make(map[string]MediaType, len(op.RequestContent))
+			for mediaType, v := range op.RequestContent {
+				content[mediaType] = MediaType{Schema: s.of(v)}
+			}
+			object.RequestBody = &RequestBody{Required: true, Content: content}
+		}
+
+		success := Response{Description: "OK"}
+		if op.Response != nil {
+			schema := s.of(op.Response)
+			success.Content = jsonContent(schema)
+			for _, mediaType := range op.ResponseTypes {
+				if strings.HasPrefix(mediaType, "text/") {
+					success.Content[mediaType] = MediaType{Schema: &Schema{Type: "string"}}
+				} else {
+					success.Content[mediaType] = MediaType{Schema: schema}
+				}
+			}
+		}
+		object.Responses["200"] = success
+
+		for _, code := range errorCodes(op) {
+			schema := errorSchema
+			if code == http.StatusUnprocessableEntity && op.Request != nil {
+				schema = validationSchema
+			}
+			object.Responses[strconv.Itoa(code)] = Response{
+				Description: http.StatusText(code),
+				Content:     jsonContent(schema),
+			}
+		}
+
+		if !op.Public {
+			object.Security = []map[string][]string{{bearerScheme: {}}}
+		}
+
+		item[strings.ToLower(op.Method)] = object
+		for _, tag := range op.Tags {
+			tags[tag] = true
+		}
+	}
+
+	for tag := range tags {
+		doc.Tags = append(doc.Tags, Tag{Name: tag})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool {
+		return doc.Tags[i].Name < doc.Tags[j].Name
+	})
+
+	doc.Components.Schemas = s.components
+
+	return doc, nil
+}
+
+func errorCodes(op Operation) []int {
+	codes := map[int]bool{http.StatusInternalServerError: true}
+
+	if op.Request != nil || len(op.RequestContent) > 0 || strings.Contains(op.Path, ":") {
+		codes[http.StatusBadRequest] = true
+	}
+	if op.Request != nil {
+		codes[http.StatusUnprocessableEntity] = true
+	}
+	if !op.Public {
+		codes[http.StatusUnauthorized] = true
+	}
+	if len(op.Scopes) > 0 {
+		codes[http.StatusForbidden] = true
+	}
+	if op.RateLimited {
+		codes[http.StatusTooManyRequests] = true
+	}
+	for _, code := range op.Errors {
+		codes[code] = true
+	}
+
+	result := make([]int, 0, len(codes))
+	for code := range codes {
+		result = append(result, code)
+	}
+	sort.Ints(result)
+
+	return result
+}
+
+func parameter(p Parameter, in string) ParameterObject {
+	typ := p.Type
+	if typ == "" {
+		typ = "string"
+	}
+
+	return ParameterObject{
+		Name:        p.Name,
+		In:          in,
+		Description: p.Description,
+		Required:    p.Required,
+		Schema:      &Schema{Type: typ},
+	}
+}
+
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{
+		"application/json": {Schema: schema},
+	}
+}
+
+// convertPath rewrites gin's ":param" segments to OpenAPI "{param}".
+func convertPath(path string) (string, []string) {
+	segments := strings.Split(path, "/")
+	var params []string
+
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			name := segment[1:]
+			params = append(params, name)
+			segments[i] = "{" + name + "}"
+		}
+	}
+
+	return strings.Join(segments, "/"), params
+}
+
+func operationId(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+
+	for _, segment := range strings.Split(path, "/") {
+		segment = strings.TrimLeft(segment, ":*.")
+		for _, part := range strings.FieldsFunc(segment, func(r rune) bool {
+			return r == '-' || r == '_' || r == '.'
+		}) {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,21 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed swagger.html
+var swaggerPage []byte
+
+// SwaggerUI serves a Swagger UI page that renders the spec at specURL.
+func SwaggerUI(specURL string) gin.HandlerFunc {
+	page := []byte(strings.ReplaceAll(string(swaggerPage), "{{SPEC_URL}}", specURL))
+
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+	}
+}
@@ -0,0 +1,165 @@
+package render
+
+import (
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var csvContentType = []string{"text/csv; charset=utf-8"}
+
+// CSV renders a slice of structs as a header row of JSON member names
+// followed by one row per element. Members hidden from JSON are hidden
+// here too; nil values are empty cells and nested values are JSON. Text
+// that a spreadsheet would run as a formula is prefixed with a quote.
+type CSV struct {
+	Data interface{}
+}
+
+func (r CSV) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, csvContentType)
+}
+
+func (r CSV) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+
+	rows := reflect.ValueOf(r.Data)
+	if rows.Kind() != reflect.Slice && rows.Kind() != reflect.Array {
+		return fmt.Errorf("render: csv needs a slice, got %T", r.Data)
+	}
+
+	elem := rows.Type().Elem()
+	for elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return fmt.Errorf("render: csv needs a slice of structs, got %T", r.Data)
+	}
+
+	columns := csvColumns(elem, nil)
+
+	writer := csv.NewWriter(w)
+
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.name
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(columns))
+	for i := 0; i < rows.Len(); i++ {
+		row := reflect.Indirect(rows.Index(i))
+		for j, column := range columns {
+			cell, err := csvCell(row, column.index)
+			if err != nil {
+				return err
+			}
+			record[j] = cell
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+type csvColumn struct {
+	name  string
+	index []int
+}
+
+func csvColumns(t reflect.Type, index []int) []csvColumn {
+	var columns []csvColumn
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		fieldIndex := append(append([]int(nil), index...), i)
+
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+			columns = append(columns, csvColumns(field.Type, fieldIndex)...)
+			continue
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+
+		columns = append(columns, csvColumn{name: name, index: fieldIndex})
+	}
+
+	return columns
+}
+
+func csvCell(row reflect.Value, index []int) (string, error) {
+	value := row.FieldByIndex(index)
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return "", nil
+		}
+		value = value.Elem()
+	}
+
+	switch v := value.Interface().(type) {
+	case time.Time:
+		return v.Format(time.RFC3339), nil
+	case encoding.TextMarshaler:
+		text, err := v.MarshalText()
+		return escapeFormula(string(text)), err
+	case fmt.Stringer:
+		return escapeFormula(v.String()), nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return escapeFormula(value.String()), nil
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64), nil
+	}
+
+	data, err := json.Marshal(value.Interface())
+	return string(data), err
+}
+
+// escapeFormula keeps spreadsheets from evaluating text such as
+// =HYPERLINK(...) by prefixing cells that start a formula with a quote.
+// Numbers are written by strconv and never pass through here.
+func escapeFormula(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+
+	return text
+}
+
+func writeContentType(w http.ResponseWriter, value []string) {
+	header := w.Header()
+	if val := header["Content-Type"]; len(val) == 0 {
+		header["Content-Type"] = value
+	}
+}
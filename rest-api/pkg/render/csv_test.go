@@ -0,0 +1,112 @@
+package render
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type csvTags []string
+
+func (t csvTags) String() string {
+	if len(t) == 0 {
+		return ""
+	}
+	return t[0]
+}
+
+type csvBase struct {
+	Id int `json:"id"`
+}
+
+type csvRow struct {
+	csvBase
+	Title       string            `json:"title"`
+	Description *string           `json:"description"`
+	Done        bool              `json:"done"`
+	Score       float64           `json:"score"`
+	Delta       int               `json:"delta"`
+	Tags        csvTags           `json:"tags"`
+	Meta        map[string]string `json:"meta"`
+	CreatedAt   time.Time         `json:"created_at"`
+	Secret      string            `json:"-"`
+	internal    string
+}
+
+func TestCSV(t *testing.T) {
+	description := "with, comma"
+	rows := []csvRow{
+		{
+			csvBase: csvBase{Id: 1}, Title: "plain", Description: &description, Done: true, Score: 1.5, Delta: -3,
+			Tags: csvTags{"home"}, Meta: map[string]string{"a": "b"}, CreatedAt: time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC),
+			Secret: "hidden", internal: "hidden",
+		},
+		{csvBase: csvBase{Id: 2}, Title: "line\nbreak \"quoted\""},
+	}
+
+	w := httptest.NewRecorder()
+	if err := (CSV{Data: rows}).Render(w); err != nil {
+		t.Fatal(err)
+	}
+
+	if contentType := w.Header().Get("Content-Type"); contentType != "text/csv; charset=utf-8" {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+
+	want := "id,title,description,done,score,delta,tags,meta,created_at\n" +
+		"1,plain,\"with, comma\",true,1.5,-3,home,\"{\"\"a\"\":\"\"b\"\"}\",2021-12-01T10:00:00Z\n" +
+		"2,\"line\nbreak \"\"quoted\"\"\",,false,0,0,,null,0001-01-01T00:00:00Z\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("body\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCSVEscapesFormulas(t *testing.T) {
+	tests := []struct {
+		title string
+		cell  string
+	}{
+		{`=HYPERLINK("http://example.com","x")`, `"'=HYPERLINK(""http://example.com"",""x"")"`},
+		{"+1", "'+1"},
+		{"-1", "'-1"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tcmd", "'\tcmd"},
+		{"\rcmd", "\"'\rcmd\""},
+		{"a=b", "a=b"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		if err := (CSV{Data: []struct {
+			Title string `json:"title"`
+			Delta int    `json:"delta"`
+		}{{Title: test.title, Delta: -1}}}).Render(w); err != nil {
+			t.Fatal(err)
+		}
+
+		want := "title,delta\n" + test.cell + ",-1\n"
+		if got := w.Body.String(); got != want {
+			t.Errorf("title %q: body %q, want %q", test.title, got, want)
+		}
+	}
+}
+
+func TestCSVNeedsSliceOfStructs(t *testing.T) {
+	for _, data := range []interface{}{csvRow{}, []int{1}} {
+		if err := (CSV{Data: data}).Render(httptest.NewRecorder()); err == nil {
+			t.Errorf("%T: no error", data)
+		}
+	}
+}
+
+func TestCSVPointerRows(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := (CSV{Data: []*csvBase{{Id: 7}}}).Render(w); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := w.Body.String(); got != "id\n7\n" {
+		t.Errorf("body %q", got)
+	}
+}
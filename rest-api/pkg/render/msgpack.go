@@ -0,0 +1,32 @@
+package render
+
+import (
+	"net/http"
+
+	"github.com/ugorji/go/codec"
+)
+
+var msgpackContentType = []string{"application/msgpack"}
+
+// msgpackHandle encodes time.Time with the MessagePack timestamp extension
+// and names struct members after their json tags.
+var msgpackHandle = func() *codec.MsgpackHandle {
+	h := &codec.MsgpackHandle{WriteExt: true}
+	h.TypeInfos = codec.NewTypeInfos([]string{"json"})
+	return h
+}()
+
+// MsgPack renders data as MessagePack. Unlike gin's renderer it keeps
+// timestamps typed instead of encoding them as opaque bytes.
+type MsgPack struct {
+	Data interface{}
+}
+
+func (r MsgPack) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, msgpackContentType)
+}
+
+func (r MsgPack) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return codec.NewEncoder(w, msgpackHandle).Encode(r.Data)
+}
@@ -0,0 +1,63 @@
+package render
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ugorji/go/codec"
+)
+
+type msgpackItem struct {
+	Id        int        `json:"id"`
+	Title     string     `json:"title"`
+	DoneAt    *time.Time `json:"done_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func TestMsgPackRoundTrip(t *testing.T) {
+	createdAt := time.Date(2021, 12, 1, 10, 0, 0, 123, time.UTC)
+	items := []msgpackItem{{Id: 1, Title: "first", CreatedAt: createdAt}, {Id: 2, Title: "second", DoneAt: &createdAt, CreatedAt: createdAt}}
+
+	w := httptest.NewRecorder()
+	if err := (MsgPack{Data: items}).Render(w); err != nil {
+		t.Fatal(err)
+	}
+
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/msgpack" {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+
+	var decoded []msgpackItem
+	if err := codec.NewDecoderBytes(w.Body.Bytes(), msgpackHandle).Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+	for i := range decoded {
+		decoded[i].CreatedAt = decoded[i].CreatedAt.UTC()
+		if decoded[i].DoneAt != nil {
+			doneAt := decoded[i].DoneAt.UTC()
+			decoded[i].DoneAt = &doneAt
+		}
+	}
+	if !reflect.DeepEqual(decoded, items) {
+		t.Errorf("decoded %+v, want %+v", decoded, items)
+	}
+}
+
+func TestMsgPackUsesJSONNames(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := (MsgPack{Data: msgpackItem{Id: 1, Title: "x"}}).Render(w); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]interface{}
+	if err := codec.NewDecoderBytes(w.Body.Bytes(), &codec.MsgpackHandle{}).Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"id", "title", "done_at", "created_at"} {
+		if _, ok := decoded[name]; !ok {
+			t.Errorf("member %q missing from %v", name, decoded)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Package render provides gin renderers for formats gin does not ship,
+// and Accept header negotiation between them.
+package render
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	JSONType    = "application/json"
+	CSVType     = "text/csv"
+	MsgPackType = "application/msgpack"
+)
+
+// aliases are media types clients send for a format under another name.
+var aliases = map[string]string{
+	"application/x-msgpack": MsgPackType,
+	"application/csv":       CSVType,
+}
+
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// Negotiate picks the offered media type the Accept header prefers. An
+// empty header accepts the first offer; "" means nothing offered is
+// acceptable.
+func Negotiate(accept string, offered ...string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offered[0]
+	}
+
+	for _, r := range parseAccept(accept) {
+		for _, offer := range offered {
+			if matches(r.mediaType, offer) {
+				return offer
+			}
+		}
+	}
+
+	return ""
+}
+
+// parseAccept returns the acceptable ranges of the header, most preferred
+// first. Ranges with q=0 are dropped.
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if mediaType == "" {
+			continue
+		}
+		if alias, ok := aliases[mediaType]; ok {
+			mediaType = alias
+		}
+
+		q := 1.0
+		for _, param := range params[1:] {
+			name, value := splitParam(param)
+			if name == "q" {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+
+		if q > 0 {
+			ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+		return specificity(ranges[i].mediaType) > specificity(ranges[j].mediaType)
+	})
+
+	return ranges
+}
+
+func splitParam(param string) (string, string) {
+	name, value := param, ""
+	if i := strings.IndexByte(param, '='); i >= 0 {
+		name, value = param[:i], param[i+1:]
+	}
+
+	return strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(value)
+}
+
+// specificity ranks type/subtype above type/* above */*.
+func specificity(mediaType string) int {
+	switch {
+	case mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(mediaType, "/*"):
+		return 1
+	default:
+		return 2
+	}
+}
+
+func matches(mediaRange, offer string) bool {
+	if mediaRange == "*/*" || mediaRange == offer {
+		return true
+	}
+
+	if strings.HasSuffix(mediaRange, "/*") {
+		return strings.HasPrefix(offer, strings.TrimSuffix(mediaRange, "*"))
+	}
+
+	return false
+}
@@ -0,0 +1,38 @@
+package render
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	offered := []string{JSONType, CSVType, MsgPackType}
+
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", JSONType},
+		{"*/*", JSONType},
+		{"text/csv", CSVType},
+		{"application/csv", CSVType},
+		{"application/x-msgpack", MsgPackType},
+		{"Text/CSV", CSVType},
+		{"text/*", CSVType},
+		{"text/csv;q=0.5, application/msgpack", MsgPackType},
+		{"text/csv;q=0.9, application/json;q=0.8", CSVType},
+		{"application/json;q=0.8, text/csv;q=0.9", CSVType},
+		{"*/*;q=0.1, text/csv;q=0.2", CSVType},
+		{"*/*, text/csv", CSVType},
+		{"application/*, text/*", JSONType},
+		{"text/csv;q=0, */*", JSONType},
+		{"text/csv;charset=utf-8", CSVType},
+		{"text/csv;q=oops", CSVType},
+		{"application/xml", ""},
+		{"text/html, image/*", ""},
+		{"text/csv;q=0", ""},
+	}
+
+	for _, test := range tests {
+		if got := Negotiate(test.accept, offered...); got != test.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", test.accept, got, test.want)
+		}
+	}
+}
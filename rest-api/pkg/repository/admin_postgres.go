@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"akhmet.com/rest-api"
+	"github.com/jmoiron/sqlx"
+)
+
+type AdminPostgres struct {
+	db *sqlx.DB
+}
+
+func NewAdminPostgres(db *sqlx.DB) *AdminPostgres {
+	return &AdminPostgres{db: db}
+}
+
+func (r *AdminPostgres) GetUsers(ctx context.Context, search string, limit, offset int) ([]todo.UserAccount, error) {
+	var users []todo.UserAccount
+	query := fmt.Sprintf(`SELECT id, name, username, role, disabled, password_reset_required, totp_enabled
+							FROM %s
+							WHERE $1 = '' OR username ILIKE $4 ESCAPE '\' OR name ILIKE $4 ESCAPE '\'
+							ORDER BY id LIMIT $2 OFFSET $3`, userTable)
+	err := r.db.SelectContext(ctx, &users, query, search, limit, offset, containsPattern(search))
+
+	return users, err
+}
+
+func (r *AdminPostgres) SetDisabled(ctx context.Context, userId int, disabled bool) error {
+	query := fmt.Sprintf("UPDATE %s SET disabled=$1 WHERE id=$2", userTable)
+	return execAffectingRows(ctx, r.db, query, disabled, userId)
+}
+
+func (r *AdminPostgres) RequirePasswordReset(ctx context.Context, userId int) error {
+	query := fmt.Sprintf("UPDATE %s SET password_reset_required=true WHERE id=$1", userTable)
+	return execAffectingRows(ctx, r.db, query, userId)
+}
+
+func (r *AdminPostgres) GetUsage(ctx context.Context) ([]todo.UserUsage, error) {
+	var usage []todo.UserUsage
+	query := fmt.Sprintf(`SELECT u.id AS user_id, u.username,
+							COUNT(DISTINCT ul.list_id) AS lists,
+							COUNT(DISTINCT li.item_id) AS items
+							FROM %s u
+							LEFT JOIN %s ul ON ul.user_id = u.id
+							LEFT JOIN %s li ON li.list_id = ul.list_id
+							GROUP BY u.id, u.username
+							ORDER BY u.id`,
+		userTable, usersListsTable, listsItemsTable)
+	err := r.db.SelectContext(ctx, &usage, query)
+
+	return usage, err
+}
+
+// TransferList makes userId the owner of the list. When the new owner is not
+// a member of the list's workspace the list moves to their personal one.
+func (r *AdminPostgres) TransferList(ctx context.Context, listId, userId int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	ownerQuery := fmt.Sprintf("UPDATE %s SET user_id=$1 WHERE list_id=$2", usersListsTable)
+	result, err := tx.ExecContext(ctx, ownerQuery, userId, listId)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		tx.Rollback()
+		if err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+
+	moveQuery := fmt.Sprintf(`UPDATE %s tl SET workspace_id = w.id
+							FROM %s w
+							WHERE tl.id = $1 AND w.personal_user_id = $2
+							AND NOT EXISTS (
+								SELECT 1 FROM %s wm WHERE wm.workspace_id = tl.workspace_id AND wm.user_id = $2
+							)`,
+		todoListsTable, workspacesTable, workspaceMembersTable)
+	if _, err := tx.ExecContext(ctx, moveQuery, listId, userId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// execAffectingRows runs an update and reports sql.ErrNoRows when nothing
+// matched, so callers can tell a missing row from a successful no-op.
+func execAffectingRows(ctx context.Context, db *sqlx.DB, query string, args ...interface{}) error {
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// containsPattern matches values containing search literally, with the LIKE
+// wildcards and the escape character in it escaped.
+func containsPattern(search string) string {
+	return "%" + likeEscaper.Replace(search) + "%"
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"akhmet.com/rest-api"
+	"github.com/jmoiron/sqlx"
+)
+
+type ApiKeyPostgres struct {
+	db *sqlx.DB
+}
+
+func NewApiKeyPostgres(db *sqlx.DB) *ApiKeyPostgres {
+	return &ApiKeyPostgres{db: db}
+}
+
+func (r *ApiKeyPostgres) Create(ctx context.Context, userId int, key todo.ApiKey, keyHash string) (int, error) {
+	var id int
+	query := fmt.Sprintf(`INSERT INTO %s (user_id, name, prefix, key_hash, scopes, expires_at)
+							VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`, apiKeysTable)
+
+	row := r.db.QueryRowContext(ctx, query, userId, key.Name, key.Prefix, keyHash, key.Scopes, key.ExpiresAt)
+	if err := row.Scan(&id); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *ApiKeyPostgres) GetAll(ctx context.Context, userId int) ([]todo.ApiKey, error) {
+	var keys []todo.ApiKey
+	query := fmt.Sprintf(`SELECT id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at
+							FROM %s WHERE user_id = $1 ORDER BY created_at`, apiKeysTable)
+	err := r.db.SelectContext(ctx, &keys, query, userId)
+
+	return keys, err
+}
+
+func (r *ApiKeyPostgres) GetByHash(ctx context.Context, keyHash string) (todo.ApiKey, error) {
+	var key todo.ApiKey
+	query := fmt.Sprintf(`SELECT id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at
+							FROM %s WHERE key_hash = $1`, apiKeysTable)
+	err := r.db.GetContext(ctx, &key, query, keyHash)
+
+	return key, err
+}
+
+func (r *ApiKeyPostgres) UpdateLastUsed(ctx context.Context, keyId int) error {
+	query := fmt.Sprintf("UPDATE %s SET last_used_at = now() WHERE id = $1", apiKeysTable)
+	_, err := r.db.ExecContext(ctx, query, keyId)
+
+	return err
+}
+
+func (r *ApiKeyPostgres) Delete(ctx context.Context, userId, keyId int) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE user_id = $1 AND id = $2", apiKeysTable)
+	_, err := r.db.ExecContext(ctx, query, userId, keyId)
+
+	return err
+}
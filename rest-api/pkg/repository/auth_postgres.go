@@ -1,9 +1,12 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
 	"akhmet.com/rest-api"
 	"github.com/jmoiron/sqlx"
 	"fmt"
+	"time"
 )
 
 type AuthPostgres struct {
@@ -14,20 +17,127 @@ func NewAuthPostgres(db *sqlx.DB) *AuthPostgres {
 	return &AuthPostgres{db: db}
 }
 
-func (r *AuthPostgres) CreateUser(user todo.User) (int, error) {
+func (r *AuthPostgres) CreateUser(ctx context.Context, user todo.User) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
 	var id int
 	query := fmt.Sprintf("INSERT INTO %s (name, username, password_hash) values ($1, $2, $3) RETURNING id", userTable)
 
-	row := r.db.QueryRow(query, user.Name, user.Username, user.Password)
+	row := tx.QueryRowContext(ctx, query, user.Name, user.Username, user.Password)
 	if err := row.Scan(&id); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err := createPersonalWorkspace(ctx, tx, id); err != nil {
+		tx.Rollback()
 		return 0, err
 	}
-	return id, nil
+
+	return id, tx.Commit()
+}
+
+func (r *AuthPostgres) GetUser(ctx context.Context, username, password string) (todo.User, error) {
+	var user todo.User
+	query := fmt.Sprintf("SELECT id, totp_enabled, role, disabled, password_reset_required FROM %s WHERE username=$1 AND password_hash=$2", userTable)
+	err := r.db.GetContext(ctx, &user, query, username, password)
+	return user, err
+}
+
+func (r *AuthPostgres) UpdatePassword(ctx context.Context, userId int, password string) error {
+	query := fmt.Sprintf("UPDATE %s SET password_hash=$1, password_reset_required=false WHERE id=$2", userTable)
+	_, err := r.db.ExecContext(ctx, query, password, userId)
+	return err
 }
 
-func (r *AuthPostgres) GetUser(username, password string) (todo.User, error) {
+func (r *AuthPostgres) GetUserById(ctx context.Context, userId int) (todo.User, error) {
 	var user todo.User
-	query := fmt.Sprintf("SELECT id FROM %s WHERE username=$1 AND password_hash=$2", userTable)
-	err := r.db.Get(&user, query, username, password)
+	query := fmt.Sprintf(`SELECT id, name, username, totp_secret, totp_enabled, role, disabled, password_reset_required
+							FROM %s WHERE id=$1`, userTable)
+	err := r.db.GetContext(ctx, &user, query, userId)
 	return user, err
 }
+
+func (r *AuthPostgres) SetTOTPSecret(ctx context.Context, userId int, secret string) error {
+	query := fmt.Sprintf("UPDATE %s SET totp_secret=$1 WHERE id=$2 AND totp_enabled=false", userTable)
+	_, err := r.db.ExecContext(ctx, query, secret, userId)
+	return err
+}
+
+func (r *AuthPostgres) EnableTOTP(ctx context.Context, userId int, recoveryCodeHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	enableQuery := fmt.Sprintf("UPDATE %s SET totp_enabled=true WHERE id=$1", userTable)
+	if _, err := tx.ExecContext(ctx, enableQuery, userId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	deleteCodesQuery := fmt.Sprintf("DELETE FROM %s WHERE user_id=$1", recoveryCodesTable)
+	if _, err := tx.ExecContext(ctx, deleteCodesQuery, userId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	createCodeQuery := fmt.Sprintf("INSERT INTO %s (user_id, code_hash) VALUES ($1, $2)", recoveryCodesTable)
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.ExecContext(ctx, createCodeQuery, userId, hash); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UseTOTPStep records step as the last one a TOTP code was accepted for. It
+// returns sql.ErrNoRows when that step or a later one was already used.
+func (r *AuthPostgres) UseTOTPStep(ctx context.Context, userId int, step int64) error {
+	query := fmt.Sprintf("UPDATE %s SET totp_last_step=$1 WHERE id=$2 AND totp_last_step < $1", userTable)
+	return execAffectingRows(ctx, r.db, query, step, userId)
+}
+
+// TakeTOTPAttempt counts an attempt at a two-factor code before the code is
+// checked, so guesses sent in parallel are counted as well. The attempt that
+// reaches maxAttempts locks verification until lockUntil; while locked it
+// fails with sql.ErrNoRows. The first attempt after a lock expires starts
+// counting again.
+func (r *AuthPostgres) TakeTOTPAttempt(ctx context.Context, userId, maxAttempts int, lockUntil, now time.Time) error {
+	attempts := "CASE WHEN totp_locked_until IS NULL THEN totp_failed_attempts + 1 ELSE 1 END"
+	query := fmt.Sprintf(`UPDATE %s SET totp_failed_attempts = %s,
+							totp_locked_until = CASE WHEN %s >= $2 THEN $3::timestamptz END
+						WHERE id=$1 AND (totp_locked_until IS NULL OR totp_locked_until <= $4)`,
+		userTable, attempts, attempts)
+	return execAffectingRows(ctx, r.db, query, userId, maxAttempts, lockUntil, now)
+}
+
+func (r *AuthPostgres) ResetTOTPAttempts(ctx context.Context, userId int) error {
+	query := fmt.Sprintf("UPDATE %s SET totp_failed_attempts=0, totp_locked_until=NULL WHERE id=$1", userTable)
+	_, err := r.db.ExecContext(ctx, query, userId)
+	return err
+}
+
+func (r *AuthPostgres) UseRecoveryCode(ctx context.Context, userId int, codeHash string) error {
+	query := fmt.Sprintf("UPDATE %s SET used=true WHERE user_id=$1 AND code_hash=$2 AND used=false", recoveryCodesTable)
+	result, err := r.db.ExecContext(ctx, query, userId, codeHash)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
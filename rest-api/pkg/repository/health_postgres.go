@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SchemaVersion is the number of the latest migration in schema/. Bump it
+// with every new migration, so instances report not ready until the
+// database has been migrated.
+const SchemaVersion = 10
+
+// schemaMigrationsTable is maintained by golang-migrate.
+const schemaMigrationsTable = "schema_migrations"
+
+type HealthPostgres struct {
+	db      *sqlx.DB
+	replica *sqlx.DB
+}
+
+func NewHealthPostgres(db, replica *sqlx.DB) *HealthPostgres {
+	return &HealthPostgres{db: db, replica: replica}
+}
+
+// Ping checks the primary and, when it is a separate database, the replica.
+func (r *HealthPostgres) Ping(ctx context.Context) error {
+	if err := r.db.PingContext(ctx); err != nil {
+		return err
+	}
+
+	if r.replica != r.db {
+		if err := r.replica.PingContext(ctx); err != nil {
+			return fmt.Errorf("replica: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetSchemaVersion returns the applied migration and whether it failed
+// half-way.
+func (r *HealthPostgres) GetSchemaVersion(ctx context.Context) (int, bool, error) {
+	var migration struct {
+		Version int  `db:"version"`
+		Dirty   bool `db:"dirty"`
+	}
+	query := fmt.Sprintf("SELECT version, dirty FROM %s LIMIT 1", schemaMigrationsTable)
+	err := r.db.GetContext(ctx, &migration, query)
+
+	return migration.Version, migration.Dirty, err
+}
+
+func (r *HealthPostgres) Stats() sql.DBStats {
+	return r.db.Stats()
+}
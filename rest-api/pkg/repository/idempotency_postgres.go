@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"akhmet.com/rest-api"
+	"github.com/jmoiron/sqlx"
+)
+
+type IdempotencyPostgres struct {
+	db *sqlx.DB
+}
+
+func NewIdempotencyPostgres(db *sqlx.DB) *IdempotencyPostgres {
+	return &IdempotencyPostgres{db: db}
+}
+
+// Reserve claims the key for a new request. When the key is already taken
+// it returns the stored record and false. The user's records created before
+// expiredBefore are dropped first so that keys can be reused after the TTL.
+func (r *IdempotencyPostgres) Reserve(ctx context.Context, userId int, key, fingerprint string, expiredBefore time.Time) (todo.IdempotencyRecord, bool, error) {
+	var record todo.IdempotencyRecord
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return record, false, err
+	}
+
+	deleteQuery := fmt.Sprintf("DELETE FROM %s WHERE user_id = $1 AND created_at < $2", idempotencyKeysTable)
+	if _, err := tx.ExecContext(ctx, deleteQuery, userId, expiredBefore); err != nil {
+		tx.Rollback()
+		return record, false, err
+	}
+
+	insertQuery := fmt.Sprintf(`INSERT INTO %s (user_id, key, fingerprint) VALUES ($1, $2, $3)
+							ON CONFLICT (user_id, key) DO NOTHING`, idempotencyKeysTable)
+	result, err := tx.ExecContext(ctx, insertQuery, userId, key, fingerprint)
+	if err != nil {
+		tx.Rollback()
+		return record, false, err
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return record, false, err
+	}
+
+	if inserted == 0 {
+		selectQuery := fmt.Sprintf(`SELECT user_id, key, fingerprint, status_code, content_type, body, created_at
+							FROM %s WHERE user_id = $1 AND key = $2`, idempotencyKeysTable)
+		if err := tx.GetContext(ctx, &record, selectQuery, userId, key); err != nil {
+			tx.Rollback()
+			return record, false, err
+		}
+	}
+
+	return record, inserted == 1, tx.Commit()
+}
+
+func (r *IdempotencyPostgres) Complete(ctx context.Context, userId int, key string, statusCode int, contentType string, body []byte) error {
+	query := fmt.Sprintf(`UPDATE %s SET status_code = $1, content_type = $2, body = $3
+							WHERE user_id = $4 AND key = $5`, idempotencyKeysTable)
+	_, err := r.db.ExecContext(ctx, query, statusCode, contentType, body, userId, key)
+
+	return err
+}
+
+func (r *IdempotencyPostgres) Release(ctx context.Context, userId int, key string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE user_id = $1 AND key = $2", idempotencyKeysTable)
+	_, err := r.db.ExecContext(ctx, query, userId, key)
+
+	return err
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"akhmet.com/rest-api"
+	"github.com/jmoiron/sqlx"
+)
+
+type IdentityPostgres struct {
+	db *sqlx.DB
+}
+
+func NewIdentityPostgres(db *sqlx.DB) *IdentityPostgres {
+	return &IdentityPostgres{db: db}
+}
+
+func (r *IdentityPostgres) GetUserByIdentity(ctx context.Context, issuer, subject string) (todo.User, error) {
+	var user todo.User
+	query := fmt.Sprintf(`SELECT u.id, u.name, u.username FROM %s u
+							INNER JOIN %s ui on ui.user_id = u.id
+							WHERE ui.issuer = $1 AND ui.subject = $2`,
+		userTable, userIdentitiesTable)
+	err := r.db.GetContext(ctx, &user, query, issuer, subject)
+	return user, err
+}
+
+func (r *IdentityPostgres) GetUserByUsername(ctx context.Context, username string) (todo.User, error) {
+	var user todo.User
+	query := fmt.Sprintf("SELECT id, name, username FROM %s WHERE username=$1", userTable)
+	err := r.db.GetContext(ctx, &user, query, username)
+	return user, err
+}
+
+func (r *IdentityPostgres) LinkIdentity(ctx context.Context, userId int, issuer, subject string) error {
+	query := fmt.Sprintf("INSERT INTO %s (user_id, issuer, subject) VALUES ($1, $2, $3)", userIdentitiesTable)
+	_, err := r.db.ExecContext(ctx, query, userId, issuer, subject)
+	return err
+}
+
+func (r *IdentityPostgres) CreateUserWithIdentity(ctx context.Context, user todo.User, issuer, subject string) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	createUserQuery := fmt.Sprintf("INSERT INTO %s (name, username, password_hash) values ($1, $2, '') RETURNING id", userTable)
+	row := tx.QueryRowContext(ctx, createUserQuery, user.Name, user.Username)
+	if err := row.Scan(&id); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err := createPersonalWorkspace(ctx, tx, id); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	linkQuery := fmt.Sprintf("INSERT INTO %s (user_id, issuer, subject) VALUES ($1, $2, $3)", userIdentitiesTable)
+	if _, err := tx.ExecContext(ctx, linkQuery, id, issuer, subject); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
@@ -7,18 +7,39 @@
 package repository
 
 import (
-	"fmt"
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"akhmet.com/rest-api/pkg/logging"
+	"github.com/XSAM/otelsql"
 	"github.com/jmoiron/sqlx"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+)
+
+const (
+	userTable             = "users"
+	todoListsTable        = "todo_lists"
+	usersListsTable       = "users_lists"
+	todoItemsTable        = "todo_items"
+	listsItemsTable       = "lists_items"
+	recoveryCodesTable    = "recovery_codes"
+	userIdentitiesTable   = "user_identities"
+	apiKeysTable          = "api_keys"
+	workspacesTable       = "workspaces"
+	workspaceMembersTable = "workspace_members"
+	idempotencyKeysTable  = "idempotency_keys"
 )
 
 const (
-	userTable       = "users"
-	todoListsTable  = "todo_lists"
-	usersListsTable = "users_lists"
-	todoItemsTable  = "todo_items"
-	listsItemsTable = "lists_items"
+	initialConnectBackoff = 500 * time.Millisecond
+	maxConnectBackoff     = 10 * time.Second
+	pingTimeout           = 5 * time.Second
 )
 
+// Config describes one database server. Zero pool settings keep the
+// database/sql defaults.
 type Config struct {
 	Host     string
 	Port     string
@@ -26,19 +47,118 @@ type Config struct {
 	Password string
 	DBName   string
 	SSLMode  string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// ConnectTimeout is how long NewPostgresDB keeps retrying while the
+	// database is not reachable, for example when it starts together with
+	// the application. Zero tries once.
+	ConnectTimeout time.Duration
 }
 
-func NewPostgresDB(cfg Config) (*sqlx.DB, error) {
-	db, err := sqlx.Open("postgres", fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.Username, cfg.DBName, cfg.Password, cfg.SSLMode))
+// DSN returns the connection string. Every value is quoted, so passwords
+// with spaces, quotes or backslashes survive.
+func (c Config) DSN() string {
+	params := []struct{ key, value string }{
+		{"host", c.Host},
+		{"port", c.Port},
+		{"user", c.Username},
+		{"password", c.Password},
+		{"dbname", c.DBName},
+		{"sslmode", c.SSLMode},
+	}
+
+	var parts []string
+	for _, p := range params {
+		if p.value == "" {
+			continue
+		}
+		parts = append(parts, p.key+"="+quoteDSNValue(p.value))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func quoteDSNValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+// NewPostgresDB opens the database through a driver wrapped for tracing,
+// so every query run with a context gets a span under the caller's span.
+func NewPostgresDB(ctx context.Context, cfg Config) (*sqlx.DB, error) {
+	driverName, err := otelsql.Register("postgres", semconv.DBSystemPostgreSQL.Value.AsString(),
+		otelsql.WithAttributes(semconv.DBNameKey.String(cfg.DBName)))
 	if err != nil {
 		return nil, err
 	}
 
-	err = db.Ping()
+	sqlDB, err := sql.Open(driverName, cfg.DSN())
 	if err != nil {
 		return nil, err
 	}
+	db := sqlx.NewDb(sqlDB, "postgres")
+
+	if cfg.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	}
+
+	if err := connect(ctx, db, cfg); err != nil {
+		db.Close()
+		return nil, err
+	}
 
 	return db, nil
 }
+
+// connect pings the database until it answers, waiting twice as long after
+// every failed attempt, up to cfg.ConnectTimeout.
+func connect(ctx context.Context, db *sqlx.DB, cfg Config) error {
+	deadline := time.Now().Add(cfg.ConnectTimeout)
+	backoff := initialConnectBackoff
+
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+		err := db.PingContext(pingCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return err
+		}
+		if wait > backoff {
+			wait = backoff
+		}
+
+		logging.FromContext(ctx).Warnf("database %s:%s is not reachable (attempt %d), retrying in %s: %s",
+			cfg.Host, cfg.Port, attempt, wait.Round(time.Millisecond), err.Error())
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+
+		backoff *= 2
+		if backoff > maxConnectBackoff {
+			backoff = maxConnectBackoff
+		}
+	}
+}
@@ -1,41 +1,136 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
+	"time"
+
 	"github.com/jmoiron/sqlx"
 	"akhmet.com/rest-api"
 )
 
 type TodoList interface {
-	Create(userId int, list todo.TodoList) (int, error)
-	GetAll(userId int) ([]todo.TodoList, error)
-	GetById(userId, listId int) (todo.TodoList, error)
-	Update(userId, listId int, input todo.UpdateListInput) error
-	Delete(userId, listId int) error
+	Create(ctx context.Context, userId, workspaceId int, list todo.TodoList) (int, error)
+	GetAll(ctx context.Context, userId, workspaceId int) ([]todo.TodoList, error)
+	GetById(ctx context.Context, userId, workspaceId, listId int) (todo.TodoList, error)
+	Update(ctx context.Context, userId, workspaceId, listId int, input todo.UpdateListInput) error
+	Patch(ctx context.Context, userId, workspaceId, listId int, apply func(todo.TodoList) (todo.TodoList, error)) (todo.TodoList, error)
+	Delete(ctx context.Context, userId, workspaceId, listId int) error
 }
 
 type Authorization interface {
-	CreateUser(user todo.User) (int, error)
-	GetUser(username, password string) (todo.User, error)
+	CreateUser(ctx context.Context, user todo.User) (int, error)
+	GetUser(ctx context.Context, username, password string) (todo.User, error)
+	GetUserById(ctx context.Context, userId int) (todo.User, error)
+	UpdatePassword(ctx context.Context, userId int, password string) error
+	SetTOTPSecret(ctx context.Context, userId int, secret string) error
+	EnableTOTP(ctx context.Context, userId int, recoveryCodeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userId int, codeHash string) error
+	UseTOTPStep(ctx context.Context, userId int, step int64) error
+	TakeTOTPAttempt(ctx context.Context, userId, maxAttempts int, lockUntil, now time.Time) error
+	ResetTOTPAttempts(ctx context.Context, userId int) error
+}
+
+type Identity interface {
+	GetUserByIdentity(ctx context.Context, issuer, subject string) (todo.User, error)
+	GetUserByUsername(ctx context.Context, username string) (todo.User, error)
+	LinkIdentity(ctx context.Context, userId int, issuer, subject string) error
+	CreateUserWithIdentity(ctx context.Context, user todo.User, issuer, subject string) (int, error)
+}
+
+type ApiKey interface {
+	Create(ctx context.Context, userId int, key todo.ApiKey, keyHash string) (int, error)
+	GetAll(ctx context.Context, userId int) ([]todo.ApiKey, error)
+	GetByHash(ctx context.Context, keyHash string) (todo.ApiKey, error)
+	UpdateLastUsed(ctx context.Context, keyId int) error
+	Delete(ctx context.Context, userId, keyId int) error
+}
+
+type Admin interface {
+	GetUsers(ctx context.Context, search string, limit, offset int) ([]todo.UserAccount, error)
+	SetDisabled(ctx context.Context, userId int, disabled bool) error
+	RequirePasswordReset(ctx context.Context, userId int) error
+	GetUsage(ctx context.Context) ([]todo.UserUsage, error)
+	TransferList(ctx context.Context, listId, userId int) error
+}
+
+type Workspace interface {
+	Create(ctx context.Context, userId int, workspace todo.Workspace) (int, error)
+	GetAll(ctx context.Context, userId int) ([]todo.Workspace, error)
+	GetById(ctx context.Context, userId, workspaceId int) (todo.Workspace, error)
+	GetPersonal(ctx context.Context, userId int) (todo.Workspace, error)
+	GetMembers(ctx context.Context, workspaceId int) ([]todo.WorkspaceMember, error)
+	AddMember(ctx context.Context, workspaceId int, username, role string) error
+	RemoveMember(ctx context.Context, workspaceId, userId int) error
+}
+
+type Idempotency interface {
+	Reserve(ctx context.Context, userId int, key, fingerprint string, expiredBefore time.Time) (todo.IdempotencyRecord, bool, error)
+	Complete(ctx context.Context, userId int, key string, statusCode int, contentType string, body []byte) error
+	Release(ctx context.Context, userId int, key string) error
+}
+
+type Health interface {
+	Ping(ctx context.Context) error
+	GetSchemaVersion(ctx context.Context) (int, bool, error)
+	Stats() sql.DBStats
 }
 
 type TodoItem interface {
-	Create(listId int, item todo.TodoItem) (int, error)
-	GetAll(userId, listId int) ([]todo.TodoItem, error)
-	GetById(userId, itemId int) (todo.TodoItem, error)
-	Update(userId, itemId int, input todo.UpdateItemInput) error
-	Delete(userId, itemId int) error
+	Create(ctx context.Context, listId int, item todo.TodoItem) (int, error)
+	GetAll(ctx context.Context, userId, workspaceId, listId int) ([]todo.TodoItem, error)
+	GetAllByLists(ctx context.Context, userId, workspaceId int, listIds []int) (map[int][]todo.TodoItem, error)
+	GetById(ctx context.Context, userId, workspaceId, itemId int) (todo.TodoItem, error)
+	Update(ctx context.Context, userId, workspaceId, itemId int, input todo.UpdateItemInput) error
+	Patch(ctx context.Context, userId, workspaceId, itemId int, apply func(todo.TodoItem) (todo.TodoItem, error)) (todo.TodoItem, error)
+	Delete(ctx context.Context, userId, workspaceId, itemId int) error
 }
 
 type Repository struct {
 	Authorization
+	Identity
+	ApiKey
+	Admin
+	Workspace
+	Idempotency
 	TodoList
 	TodoItem
+	Health
+}
+
+type primaryKey struct{}
+
+// WithPrimary sends the list and item reads made with ctx to the primary,
+// for reads that must see a write made just before them.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
 }
 
-func NewRepository(db *sqlx.DB) *Repository {
+// reader returns db when ctx comes from WithPrimary and replica otherwise.
+func reader(ctx context.Context, db, replica *sqlx.DB) *sqlx.DB {
+	if primary, _ := ctx.Value(primaryKey{}).(bool); primary {
+		return db
+	}
+
+	return replica
+}
+
+// NewRepository routes the list and item reads to replica when it is not
+// nil and everything else to db.
+func NewRepository(db, replica *sqlx.DB) *Repository {
+	if replica == nil {
+		replica = db
+	}
+
 	return &Repository{
 		Authorization: NewAuthPostgres(db),
-		TodoList:      NewTodoListPostgres(db),
-		TodoItem: 	   NewTodoItemPostgres(db),
+		Identity:      NewIdentityPostgres(db),
+		ApiKey:        NewApiKeyPostgres(db),
+		Admin:         NewAdminPostgres(db),
+		Workspace:     NewWorkspacePostgres(db),
+		Idempotency:   NewIdempotencyPostgres(db),
+		TodoList:      NewTodoListPostgres(db, replica),
+		TodoItem: 	   NewTodoItemPostgres(db, replica),
+		Health:        NewHealthPostgres(db, replica),
 	}
 }
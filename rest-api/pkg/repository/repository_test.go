@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestReaderUsesPrimaryForWithPrimary(t *testing.T) {
+	db, replica := sqlx.NewDb(nil, "postgres"), sqlx.NewDb(nil, "postgres")
+
+	if got := reader(context.Background(), db, replica); got != replica {
+		t.Fatal("plain reads should go to the replica")
+	}
+	if got := reader(WithPrimary(context.Background()), db, replica); got != db {
+		t.Fatal("reads with WithPrimary should go to the primary")
+	}
+}
+
+func TestContainsPatternEscapesWildcards(t *testing.T) {
+	tests := map[string]string{
+		"":        "%%",
+		"alice":   "%alice%",
+		"100%":    `%100\%%`,
+		"a_b":     `%a\_b%`,
+		`back\sl`: `%back\\sl%`,
+	}
+
+	for search, want := range tests {
+		if got := containsPattern(search); got != want {
+			t.Errorf("containsPattern(%q) = %q, want %q", search, got, want)
+		}
+	}
+}
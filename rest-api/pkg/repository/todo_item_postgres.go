@@ -1,22 +1,29 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"akhmet.com/rest-api"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
+// TodoItemPostgres runs GetAll, GetAllByLists and GetById on replica,
+// which is the primary when no replica is configured, so they may lag
+// behind writes by the replication delay. Contexts from WithPrimary read
+// from the primary.
 type TodoItemPostgres struct {
-	db *sqlx.DB
+	db      *sqlx.DB
+	replica *sqlx.DB
 }
 
-func NewTodoItemPostgres(db *sqlx.DB) *TodoItemPostgres {
-	return &TodoItemPostgres{db: db}
+func NewTodoItemPostgres(db, replica *sqlx.DB) *TodoItemPostgres {
+	return &TodoItemPostgres{db: db, replica: replica}
 }
 
-func (r *TodoItemPostgres) Create(listId int, item todo.TodoItem) (int, error) {
-	tx, err := r.db.Begin()
+func (r *TodoItemPostgres) Create(ctx context.Context, listId int, item todo.TodoItem) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, err
 	}
@@ -25,7 +32,7 @@ func (r *TodoItemPostgres) Create(listId int, item todo.TodoItem) (int, error) {
 	createItemQuery := fmt.Sprintf("INSERT INTO %s (title, description) values ($1, $2) RETURNING id",
 		todoItemsTable)
 
-	row := tx.QueryRow(createItemQuery, item.Title, item.Description)
+	row := tx.QueryRowContext(ctx, createItemQuery, item.Title, item.Description)
 	err = row.Scan(&itemId)
 	if err != nil {
 		tx.Rollback()
@@ -34,7 +41,7 @@ func (r *TodoItemPostgres) Create(listId int, item todo.TodoItem) (int, error) {
 
 	createListItemsQuery := fmt.Sprintf("INSERT INTO %s (list_id, item_id) values ($1, $2)",
 		listsItemsTable)
-	_, err = tx.Exec(createListItemsQuery, listId, itemId)
+	_, err = tx.ExecContext(ctx, createListItemsQuery, listId, itemId)
 	if err != nil {
 		tx.Rollback()
 		return 0, err
@@ -43,45 +50,75 @@ func (r *TodoItemPostgres) Create(listId int, item todo.TodoItem) (int, error) {
 	return itemId, tx.Commit()
 }
 
-func (r *TodoItemPostgres) GetAll(userId, listId int) ([]todo.TodoItem, error) {
+func (r *TodoItemPostgres) GetAll(ctx context.Context, userId, workspaceId, listId int) ([]todo.TodoItem, error) {
 	var items []todo.TodoItem
-	query := fmt.Sprintf(`SELECT ti.id, ti.title, ti.description FROM %s ti
+	query := fmt.Sprintf(`SELECT ti.id, ti.title, ti.description, ti.done FROM %s ti
 							INNER JOIN %s li on li.item_id = ti.id
-							INNER JOIN %s ul on ul.list_id = li.list_id
-							WHERE li.list_id = $1 AND ul.user_id = $2`,
-	todoItemsTable, listsItemsTable, usersListsTable)
-	if err := r.db.Select(&items, query, listId, userId); err != nil {
+							INNER JOIN %s tl on tl.id = li.list_id
+							INNER JOIN %s wm on wm.workspace_id = tl.workspace_id
+							WHERE li.list_id = $1 AND wm.user_id = $2 AND tl.workspace_id = $3`,
+	todoItemsTable, listsItemsTable, todoListsTable, workspaceMembersTable)
+	if err := reader(ctx, r.db, r.replica).SelectContext(ctx, &items, query, listId, userId, workspaceId); err != nil {
 		return nil, err
 	}
 
 	return items, nil
 }
 
-func (r *TodoItemPostgres) GetById(userId, listId int) (todo.TodoItem, error) {
+func (r *TodoItemPostgres) GetAllByLists(ctx context.Context, userId, workspaceId int, listIds []int) (map[int][]todo.TodoItem, error) {
+	var rows []struct {
+		ListId int `db:"list_id"`
+		todo.TodoItem
+	}
+	query := fmt.Sprintf(`SELECT li.list_id, ti.id, ti.title, ti.description, ti.done FROM %s ti
+							INNER JOIN %s li on li.item_id = ti.id
+							INNER JOIN %s tl on tl.id = li.list_id
+							INNER JOIN %s wm on wm.workspace_id = tl.workspace_id
+							WHERE li.list_id = ANY($1) AND wm.user_id = $2 AND tl.workspace_id = $3
+							ORDER BY ti.id`,
+		todoItemsTable, listsItemsTable, todoListsTable, workspaceMembersTable)
+	if err := reader(ctx, r.db, r.replica).SelectContext(ctx, &rows, query, pq.Array(listIds), userId, workspaceId); err != nil {
+		return nil, err
+	}
+
+	items := make(map[int][]todo.TodoItem, len(listIds))
+	for _, row := range rows {
+		items[row.ListId] = append(items[row.ListId], row.TodoItem)
+	}
+
+	return items, nil
+}
+
+func (r *TodoItemPostgres) GetById(ctx context.Context, userId, workspaceId, itemId int) (todo.TodoItem, error) {
 	var item todo.TodoItem
-	query := fmt.Sprintf(`SELECT ti.id, ti.title, ti.description, ti.done FROM %s ti INNER JOIN %s li on li.item_id = ti.id
-									INNER JOIN %s ul on ul.list_id = li.list_id WHERE ti.id = $1 AND ul.user_id = $2`,
-		todoItemsTable, listsItemsTable, usersListsTable)
-	if err := r.db.Get(&item, query, listId, userId); err != nil {
+	query := fmt.Sprintf(`SELECT ti.id, ti.title, ti.description, ti.done FROM %s ti
+							INNER JOIN %s li on li.item_id = ti.id
+							INNER JOIN %s tl on tl.id = li.list_id
+							INNER JOIN %s wm on wm.workspace_id = tl.workspace_id
+							WHERE ti.id = $1 AND wm.user_id = $2 AND tl.workspace_id = $3`,
+		todoItemsTable, listsItemsTable, todoListsTable, workspaceMembersTable)
+	if err := reader(ctx, r.db, r.replica).GetContext(ctx, &item, query, itemId, userId, workspaceId); err != nil {
 		return item, err
 	}
 
 	return item, nil
 }
 
-func (r *TodoItemPostgres) Delete(userId, itemId int) error {
-	query := fmt.Sprintf(`DELETE FROM %s ti USING %s li, %s ul 
+func (r *TodoItemPostgres) Delete(ctx context.Context, userId, workspaceId, itemId int) error {
+	query := fmt.Sprintf(`DELETE FROM %s ti USING %s li, %s tl, %s wm
 							WHERE ti.id = li.item_id
-							AND li.list_id = ul.list_id
-							AND ul.user_id = $1 
-							AND ti.id = $2`,
-		todoItemsTable, listsItemsTable, usersListsTable)
-	_, err := r.db.Exec(query, userId, itemId)
+							AND li.list_id = tl.id
+							AND tl.workspace_id = wm.workspace_id
+							AND wm.user_id = $1
+							AND tl.workspace_id = $2
+							AND ti.id = $3`,
+		todoItemsTable, listsItemsTable, todoListsTable, workspaceMembersTable)
+	_, err := r.db.ExecContext(ctx, query, userId, workspaceId, itemId)
 
 	return err
 }
 
-func (r *TodoItemPostgres) Update(userId, itemId int, input todo.UpdateItemInput) error {
+func (r *TodoItemPostgres) Update(ctx context.Context, userId, workspaceId, itemId int, input todo.UpdateItemInput) error {
 	setValue := make([]string, 0)
 	args := make([]interface{}, 0)
 	argId := 1
@@ -107,12 +144,49 @@ func (r *TodoItemPostgres) Update(userId, itemId int, input todo.UpdateItemInput
 	setQuery := strings.Join(setValue, ", ")
 
 	query := fmt.Sprintf(`UPDATE %s ti SET %s
-							FROM %s li, %s ul WHERE ti.id = li.item_id
-							AND li.list_id = ul.list_id
-							AND ti.id = $%d AND ul.user_id = $%d`,
-		todoItemsTable, setQuery, listsItemsTable, usersListsTable, argId, argId + 1)
-	args = append(args, itemId, userId)
-
-	_, err := r.db.Exec(query, args...)
+							FROM %s li, %s tl, %s wm WHERE ti.id = li.item_id
+							AND li.list_id = tl.id
+							AND tl.workspace_id = wm.workspace_id
+							AND ti.id = $%d AND wm.user_id = $%d AND tl.workspace_id = $%d`,
+		todoItemsTable, setQuery, listsItemsTable, todoListsTable, workspaceMembersTable, argId, argId + 1, argId + 2)
+	args = append(args, itemId, userId, workspaceId)
+
+	_, err := r.db.ExecContext(ctx, query, args...)
 	return err
-}
\ No newline at end of file
+}
+
+// Patch reads the item from the primary with its row locked, passes it to
+// apply and overwrites every editable column with the result, including
+// setting a nil description to NULL. Concurrent patches of the same item
+// wait for each other instead of losing updates.
+func (r *TodoItemPostgres) Patch(ctx context.Context, userId, workspaceId, itemId int, apply func(todo.TodoItem) (todo.TodoItem, error)) (todo.TodoItem, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return todo.TodoItem{}, err
+	}
+	defer tx.Rollback()
+
+	var item todo.TodoItem
+	selectQuery := fmt.Sprintf(`SELECT ti.id, ti.title, ti.description, ti.done FROM %s ti
+							INNER JOIN %s li on li.item_id = ti.id
+							INNER JOIN %s tl on tl.id = li.list_id
+							INNER JOIN %s wm on wm.workspace_id = tl.workspace_id
+							WHERE ti.id = $1 AND wm.user_id = $2 AND tl.workspace_id = $3
+							FOR UPDATE OF ti`,
+		todoItemsTable, listsItemsTable, todoListsTable, workspaceMembersTable)
+	if err := tx.GetContext(ctx, &item, selectQuery, itemId, userId, workspaceId); err != nil {
+		return todo.TodoItem{}, err
+	}
+
+	patched, err := apply(item)
+	if err != nil {
+		return todo.TodoItem{}, err
+	}
+
+	updateQuery := fmt.Sprintf("UPDATE %s SET title = $1, description = $2, done = $3 WHERE id = $4", todoItemsTable)
+	if _, err := tx.ExecContext(ctx, updateQuery, patched.Title, patched.Description, patched.Done, itemId); err != nil {
+		return todo.TodoItem{}, err
+	}
+
+	return patched, tx.Commit()
+}
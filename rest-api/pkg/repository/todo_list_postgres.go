@@ -1,37 +1,42 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"akhmet.com/rest-api"
 	"github.com/jmoiron/sqlx"
-	"github.com/sirupsen/logrus"
+	"akhmet.com/rest-api/pkg/logging"
 )
 
+// TodoListPostgres runs GetAll and GetById on replica, which is the
+// primary when no replica is configured, so they may lag behind writes by
+// the replication delay. Contexts from WithPrimary read from the primary.
 type TodoListPostgres struct {
-	db *sqlx.DB
+	db      *sqlx.DB
+	replica *sqlx.DB
 }
 
-func NewTodoListPostgres(db *sqlx.DB) *TodoListPostgres {
-	return &TodoListPostgres{db: db}
+func NewTodoListPostgres(db, replica *sqlx.DB) *TodoListPostgres {
+	return &TodoListPostgres{db: db, replica: replica}
 }
 
-func (r *TodoListPostgres) Create(userId int, list todo.TodoList) (int, error) {
-	tx, err := r.db.Begin()
+func (r *TodoListPostgres) Create(ctx context.Context, userId, workspaceId int, list todo.TodoList) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, err
 	}
 
 	var id int
-	createListQuery := fmt.Sprintf("INSERT INTO %s (title, description) VALUES ($1, $2) RETURNING id", todoListsTable)
-	row := tx.QueryRow(createListQuery, list.Title, list.Description)
+	createListQuery := fmt.Sprintf("INSERT INTO %s (title, description, workspace_id) VALUES ($1, $2, $3) RETURNING id", todoListsTable)
+	row := tx.QueryRowContext(ctx, createListQuery, list.Title, list.Description, workspaceId)
 	if err := row.Scan(&id); err != nil {
 		tx.Rollback()
 		return 0, err
 	}
 
 	createUsersListQuery := fmt.Sprintf("INSERT INTO %s (user_id, list_id) VALUES ($1, $2)", usersListsTable)
-	_, err = tx.Exec(createUsersListQuery, userId, id)
+	_, err = tx.ExecContext(ctx, createUsersListQuery, userId, id)
 	if err != nil {
 		tx.Rollback()
 		return 0, err
@@ -40,41 +45,45 @@ func (r *TodoListPostgres) Create(userId int, list todo.TodoList) (int, error) {
 	return id, tx.Commit()
 }
 
-func (r *TodoListPostgres) GetAll(userId int) ([]todo.TodoList, error) {
+func (r *TodoListPostgres) GetAll(ctx context.Context, userId, workspaceId int) ([]todo.TodoList, error) {
 	var lists []todo.TodoList
 
-	query := fmt.Sprintf("SELECT tl.id, tl.title, tl.description FROM %s tl INNER JOIN %s ul on tl.id = ul.list_id WHERE ul.user_id = $1",
-		todoListsTable, usersListsTable)
-	err := r.db.Select(&lists, query, userId)
+	query := fmt.Sprintf(`SELECT tl.id, tl.workspace_id, tl.title, tl.description
+							FROM %s tl INNER JOIN %s wm
+							ON tl.workspace_id = wm.workspace_id
+							WHERE wm.user_id = $1 AND tl.workspace_id = $2`,
+		todoListsTable, workspaceMembersTable)
+	err := reader(ctx, r.db, r.replica).SelectContext(ctx, &lists, query, userId, workspaceId)
 
 	return lists, err
 }
 
-func (r *TodoListPostgres) GetById(userId, listId int) (todo.TodoList, error) {
+func (r *TodoListPostgres) GetById(ctx context.Context, userId, workspaceId, listId int) (todo.TodoList, error) {
 	var lists todo.TodoList
 
-	query := fmt.Sprintf(`SELECT tl.id, tl.title, tl.description 
-							FROM %s tl INNER JOIN %s ul 
-							ON tl.id = ul.list_id 
-							WHERE ul.user_id = $1 AND ul.list_id = $2`,
-		todoListsTable, usersListsTable)
-	err := r.db.Get(&lists, query, userId, listId)
+	query := fmt.Sprintf(`SELECT tl.id, tl.workspace_id, tl.title, tl.description
+							FROM %s tl INNER JOIN %s wm
+							ON tl.workspace_id = wm.workspace_id
+							WHERE wm.user_id = $1 AND tl.workspace_id = $2 AND tl.id = $3`,
+		todoListsTable, workspaceMembersTable)
+	err := reader(ctx, r.db, r.replica).GetContext(ctx, &lists, query, userId, workspaceId, listId)
 
 	return lists, err
 }
 
-func (r *TodoListPostgres) Delete(userId, listId int) error {
-	query := fmt.Sprintf(`DELETE FROM %s tl USING %s ul 
-							WHERE tl.id = ul.list_id 
-							AND ul.user_id = $1 
-							AND ul.list_id = $2`,
-		todoListsTable, usersListsTable)
-	_, err := r.db.Exec(query, userId, listId)
+func (r *TodoListPostgres) Delete(ctx context.Context, userId, workspaceId, listId int) error {
+	query := fmt.Sprintf(`DELETE FROM %s tl USING %s wm
+							WHERE tl.workspace_id = wm.workspace_id
+							AND wm.user_id = $1
+							AND tl.workspace_id = $2
+							AND tl.id = $3`,
+		todoListsTable, workspaceMembersTable)
+	_, err := r.db.ExecContext(ctx, query, userId, workspaceId, listId)
 
 	return err
 }
 
-func (r *TodoListPostgres) Update(userId, listId int, input todo.UpdateListInput) error {
+func (r *TodoListPostgres) Update(ctx context.Context, userId, workspaceId, listId int, input todo.UpdateListInput) error {
 	setValue := make([]string, 0)
 	args := make([]interface{}, 0)
 	argId := 1
@@ -94,14 +103,49 @@ func (r *TodoListPostgres) Update(userId, listId int, input todo.UpdateListInput
 	setQuery := strings.Join(setValue, ", ")
 
 	query := fmt.Sprintf(`UPDATE %s tl SET %s
-							FROM %s ul WHERE tl.id = ul.list_id
-							AND ul.list_id = $%d AND ul.user_id = $%d`,
-							todoListsTable, setQuery, usersListsTable, argId, argId + 1)
-	args = append(args, listId, userId)
+							FROM %s wm WHERE tl.workspace_id = wm.workspace_id
+							AND tl.id = $%d AND wm.user_id = $%d AND tl.workspace_id = $%d`,
+							todoListsTable, setQuery, workspaceMembersTable, argId, argId + 1, argId + 2)
+	args = append(args, listId, userId, workspaceId)
 
-	logrus.Debugf("updateQuery: %s", query)
-	logrus.Debugf("args: %s", args)
+	logging.FromContext(ctx).Debugf("updateQuery: %s", query)
+	logging.FromContext(ctx).Debugf("args: %s", args)
 
-	_, err := r.db.Exec(query, args...)
+	_, err := r.db.ExecContext(ctx, query, args...)
 	return err
 }
+
+// Patch reads the list from the primary with its row locked, passes it to
+// apply and overwrites every editable column with the result, including
+// setting a nil description to NULL. Concurrent patches of the same list
+// wait for each other instead of losing updates.
+func (r *TodoListPostgres) Patch(ctx context.Context, userId, workspaceId, listId int, apply func(todo.TodoList) (todo.TodoList, error)) (todo.TodoList, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return todo.TodoList{}, err
+	}
+	defer tx.Rollback()
+
+	var list todo.TodoList
+	selectQuery := fmt.Sprintf(`SELECT tl.id, tl.workspace_id, tl.title, tl.description
+							FROM %s tl INNER JOIN %s wm
+							ON tl.workspace_id = wm.workspace_id
+							WHERE wm.user_id = $1 AND tl.workspace_id = $2 AND tl.id = $3
+							FOR UPDATE OF tl`,
+		todoListsTable, workspaceMembersTable)
+	if err := tx.GetContext(ctx, &list, selectQuery, userId, workspaceId, listId); err != nil {
+		return todo.TodoList{}, err
+	}
+
+	patched, err := apply(list)
+	if err != nil {
+		return todo.TodoList{}, err
+	}
+
+	updateQuery := fmt.Sprintf("UPDATE %s SET title = $1, description = $2 WHERE id = $3", todoListsTable)
+	if _, err := tx.ExecContext(ctx, updateQuery, patched.Title, patched.Description, listId); err != nil {
+		return todo.TodoList{}, err
+	}
+
+	return patched, tx.Commit()
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"akhmet.com/rest-api"
+	"github.com/jmoiron/sqlx"
+)
+
+type WorkspacePostgres struct {
+	db *sqlx.DB
+}
+
+func NewWorkspacePostgres(db *sqlx.DB) *WorkspacePostgres {
+	return &WorkspacePostgres{db: db}
+}
+
+func (r *WorkspacePostgres) Create(ctx context.Context, userId int, workspace todo.Workspace) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	createWorkspaceQuery := fmt.Sprintf("INSERT INTO %s (name) VALUES ($1) RETURNING id", workspacesTable)
+	row := tx.QueryRowContext(ctx, createWorkspaceQuery, workspace.Name)
+	if err := row.Scan(&id); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	createMemberQuery := fmt.Sprintf("INSERT INTO %s (workspace_id, user_id, role) VALUES ($1, $2, $3)", workspaceMembersTable)
+	if _, err := tx.ExecContext(ctx, createMemberQuery, id, userId, todo.WorkspaceRoleOwner); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+func (r *WorkspacePostgres) GetAll(ctx context.Context, userId int) ([]todo.Workspace, error) {
+	var workspaces []todo.Workspace
+	query := fmt.Sprintf(`SELECT w.id, w.name, w.personal_user_id IS NOT NULL AS personal, wm.role
+							FROM %s w INNER JOIN %s wm ON wm.workspace_id = w.id
+							WHERE wm.user_id = $1 ORDER BY w.id`,
+		workspacesTable, workspaceMembersTable)
+	err := r.db.SelectContext(ctx, &workspaces, query, userId)
+
+	return workspaces, err
+}
+
+func (r *WorkspacePostgres) GetById(ctx context.Context, userId, workspaceId int) (todo.Workspace, error) {
+	var workspace todo.Workspace
+	query := fmt.Sprintf(`SELECT w.id, w.name, w.personal_user_id IS NOT NULL AS personal, wm.role
+							FROM %s w INNER JOIN %s wm ON wm.workspace_id = w.id
+							WHERE wm.user_id = $1 AND w.id = $2`,
+		workspacesTable, workspaceMembersTable)
+	err := r.db.GetContext(ctx, &workspace, query, userId, workspaceId)
+
+	return workspace, err
+}
+
+func (r *WorkspacePostgres) GetPersonal(ctx context.Context, userId int) (todo.Workspace, error) {
+	var workspace todo.Workspace
+	query := fmt.Sprintf(`SELECT w.id, w.name, true AS personal, wm.role
+							FROM %s w INNER JOIN %s wm ON wm.workspace_id = w.id
+							WHERE w.personal_user_id = $1 AND wm.user_id = $1`,
+		workspacesTable, workspaceMembersTable)
+	err := r.db.GetContext(ctx, &workspace, query, userId)
+
+	return workspace, err
+}
+
+func (r *WorkspacePostgres) GetMembers(ctx context.Context, workspaceId int) ([]todo.WorkspaceMember, error) {
+	var members []todo.WorkspaceMember
+	query := fmt.Sprintf(`SELECT wm.user_id, u.username, wm.role
+							FROM %s wm INNER JOIN %s u ON u.id = wm.user_id
+							WHERE wm.workspace_id = $1 ORDER BY wm.id`,
+		workspaceMembersTable, userTable)
+	err := r.db.SelectContext(ctx, &members, query, workspaceId)
+
+	return members, err
+}
+
+func (r *WorkspacePostgres) AddMember(ctx context.Context, workspaceId int, username, role string) error {
+	query := fmt.Sprintf(`INSERT INTO %s (workspace_id, user_id, role)
+							SELECT $1, id, $3 FROM %s WHERE username = $2`,
+		workspaceMembersTable, userTable)
+	return execAffectingRows(ctx, r.db, query, workspaceId, username, role)
+}
+
+func (r *WorkspacePostgres) RemoveMember(ctx context.Context, workspaceId, userId int) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE workspace_id = $1 AND user_id = $2", workspaceMembersTable)
+	return execAffectingRows(ctx, r.db, query, workspaceId, userId)
+}
+
+// createPersonalWorkspace gives a newly created user the workspace their
+// lists go to when no other workspace is selected.
+func createPersonalWorkspace(ctx context.Context, tx *sql.Tx, userId int) error {
+	var id int
+	createWorkspaceQuery := fmt.Sprintf("INSERT INTO %s (name, personal_user_id) VALUES ($1, $2) RETURNING id", workspacesTable)
+	if err := tx.QueryRowContext(ctx, createWorkspaceQuery, "Personal", userId).Scan(&id); err != nil {
+		return err
+	}
+
+	createMemberQuery := fmt.Sprintf("INSERT INTO %s (workspace_id, user_id, role) VALUES ($1, $2, $3)", workspaceMembersTable)
+	_, err := tx.ExecContext(ctx, createMemberQuery, id, userId, todo.WorkspaceRoleOwner)
+
+	return err
+}
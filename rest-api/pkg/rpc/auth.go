@@ -0,0 +1,74 @@
+package rpc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/rpc/todopb"
+	"akhmet.com/rest-api/pkg/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type authServer struct {
+	todopb.UnimplementedAuthorizationServiceServer
+	services *service.Service
+}
+
+func (s *authServer) SignUp(ctx context.Context, req *todopb.SignUpRequest) (*todopb.SignUpResponse, error) {
+	id, err := s.services.Authorization.CreateUser(ctx, todo.User{
+		Name:     req.Name,
+		Username: req.Username,
+		Password: req.Password,
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &todopb.SignUpResponse{Id: int64(id)}, nil
+}
+
+func (s *authServer) SignIn(ctx context.Context, req *todopb.SignInRequest) (*todopb.SignInResponse, error) {
+	user, err := s.services.Authorization.Authenticate(ctx, req.Username, req.Password)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Error(codes.Unauthenticated, "invalid username or password")
+	}
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	if user.TOTPEnabled {
+		challenge, err := s.services.TwoFactor.GenerateChallengeToken(user.Id)
+		if err != nil {
+			return nil, statusError(err)
+		}
+
+		return &todopb.SignInResponse{TwoFactorRequired: true, ChallengeToken: challenge}, nil
+	}
+
+	token, err := s.services.Authorization.GenerateToken(ctx, user.Id, todo.AllScopes)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &todopb.SignInResponse{Token: token}, nil
+}
+
+func (s *authServer) SignInTwoFactor(ctx context.Context, req *todopb.SignInTwoFactorRequest) (*todopb.SignInTwoFactorResponse, error) {
+	userId, err := s.services.TwoFactor.VerifyChallenge(ctx, req.ChallengeToken, req.Code)
+	if err != nil {
+		if errors.Is(err, service.ErrTwoFactorLocked) {
+			return nil, statusError(err)
+		}
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	token, err := s.services.Authorization.GenerateToken(ctx, userId, todo.AllScopes)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &todopb.SignInTwoFactorResponse{Token: token}, nil
+}
@@ -0,0 +1,55 @@
+package rpc
+
+import (
+	"database/sql"
+	"errors"
+
+	"akhmet.com/rest-api/pkg/service"
+	"akhmet.com/rest-api/pkg/validate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// statusError maps domain errors to grpc status codes.
+func statusError(err error) error {
+	var errs validate.Errors
+	if errors.As(err, &errs) {
+		return invalidArgument(errs)
+	}
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return status.Error(codes.NotFound, "not found")
+	case errors.Is(err, service.ErrUserDisabled),
+		errors.Is(err, service.ErrPasswordResetRequired):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, service.ErrInvalidTwoFactorCode),
+		errors.Is(err, service.ErrInvalidChallengeToken),
+		errors.Is(err, service.ErrInvalidApiKey),
+		errors.Is(err, service.ErrApiKeyExpired):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, service.ErrTwoFactorLocked):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// invalidArgument reports every failing field as a BadRequest detail.
+func invalidArgument(errs validate.Errors) error {
+	details := &errdetails.BadRequest{}
+	for _, fieldError := range errs {
+		details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       fieldError.Field,
+			Description: fieldError.Code + ": " + fieldError.Message,
+		})
+	}
+
+	st, err := status.New(codes.InvalidArgument, errs.Error()).WithDetails(details)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, errs.Error())
+	}
+
+	return st.Err()
+}
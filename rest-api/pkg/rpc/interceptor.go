@@ -0,0 +1,129 @@
+package rpc
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"akhmet.com/rest-api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	authorizationMetadata = "authorization"
+	workspaceMetadata     = "x-workspace-id"
+	publicService         = "/todo.v1.AuthorizationService/"
+)
+
+// methodScopes mirrors the per-route scopes of the REST API. Methods missing
+// from the table are rejected.
+var methodScopes = map[string][]string{
+	"/todo.v1.TodoListService/CreateList": {todo.ScopeListsWrite},
+	"/todo.v1.TodoListService/ListLists":  {todo.ScopeListsRead},
+	"/todo.v1.TodoListService/GetList":    {todo.ScopeListsRead},
+	"/todo.v1.TodoListService/UpdateList": {todo.ScopeListsWrite},
+	"/todo.v1.TodoListService/DeleteList": {todo.ScopeListsWrite},
+	"/todo.v1.TodoItemService/CreateItem": {todo.ScopeItemsWrite},
+	"/todo.v1.TodoItemService/ListItems":  {todo.ScopeItemsRead},
+	"/todo.v1.TodoItemService/GetItem":    {todo.ScopeItemsRead},
+	"/todo.v1.TodoItemService/UpdateItem": {todo.ScopeItemsWrite},
+	"/todo.v1.TodoItemService/DeleteItem": {todo.ScopeItemsWrite},
+}
+
+type callerKey struct{}
+
+type caller struct {
+	userId      int
+	workspaceId int
+}
+
+func callerFrom(ctx context.Context) caller {
+	c, _ := ctx.Value(callerKey{}).(caller)
+	return c
+}
+
+// authenticate reads the bearer token (a JWT or an api key) and the
+// workspace from the request metadata, the same way the REST middlewares
+// read the Authorization and X-Workspace-Id headers.
+func (s *Server) authenticate(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if strings.HasPrefix(info.FullMethod, publicService) {
+		return handler(ctx, req)
+	}
+
+	scopes, ok := methodScopes[info.FullMethod]
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "method is not allowed")
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	token := strings.TrimPrefix(first(md, authorizationMetadata), "Bearer ")
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "empty authorization metadata")
+	}
+
+	var principal todo.Principal
+	if s.services.ApiKey.IsApiKey(token) {
+		key, err := s.services.ApiKey.ParseKey(ctx, token)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		principal = todo.Principal{UserId: key.UserId, Scopes: key.Scopes}
+	} else {
+		var err error
+		principal, err = s.services.Authorization.ParseToken(ctx, token)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+	}
+
+	if missing := principal.Scopes.Missing(scopes...); len(missing) > 0 {
+		return nil, status.Error(codes.PermissionDenied, "missing scope: "+strings.Join(missing, ", "))
+	}
+
+	workspaceId, err := s.workspace(ctx, principal.UserId, first(md, workspaceMetadata))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = identified(ctx, principal.UserId, workspaceId)
+
+	return handler(context.WithValue(ctx, callerKey{}, caller{
+		userId:      principal.UserId,
+		workspaceId: workspaceId,
+	}), req)
+}
+
+func (s *Server) workspace(ctx context.Context, userId int, value string) (int, error) {
+	if value == "" {
+		workspace, err := s.services.Workspace.GetPersonal(ctx, userId)
+		if err != nil {
+			return 0, statusError(err)
+		}
+
+		return workspace.Id, nil
+	}
+
+	workspaceId, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, status.Error(codes.InvalidArgument, "invalid workspace metadata")
+	}
+
+	if _, err := s.services.Workspace.GetById(ctx, userId, workspaceId); err != nil {
+		return 0, status.Error(codes.PermissionDenied, "not a member of the workspace")
+	}
+
+	return workspaceId, nil
+}
+
+func first(md metadata.MD, key string) string {
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
@@ -0,0 +1,84 @@
+package rpc
+
+import (
+	"context"
+
+	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/rpc/todopb"
+	"akhmet.com/rest-api/pkg/service"
+)
+
+type itemServer struct {
+	todopb.UnimplementedTodoItemServiceServer
+	services *service.Service
+}
+
+func toItemMessage(item todo.TodoItem) *todopb.TodoItem {
+	return &todopb.TodoItem{
+		Id:          int64(item.Id),
+		Title:       item.Title,
+		Description: item.Description,
+		Done:        item.Done,
+	}
+}
+
+func (s *itemServer) CreateItem(ctx context.Context, req *todopb.CreateItemRequest) (*todopb.CreateItemResponse, error) {
+	c := callerFrom(ctx)
+	id, err := s.services.TodoItem.Create(ctx, c.userId, c.workspaceId, int(req.ListId), todo.TodoItem{
+		Title:       req.Title,
+		Description: req.Description,
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &todopb.CreateItemResponse{Id: int64(id)}, nil
+}
+
+func (s *itemServer) ListItems(ctx context.Context, req *todopb.ListItemsRequest) (*todopb.ListItemsResponse, error) {
+	c := callerFrom(ctx)
+	items, err := s.services.TodoItem.GetAll(ctx, c.userId, c.workspaceId, int(req.ListId))
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	response := &todopb.ListItemsResponse{Items: make([]*todopb.TodoItem, 0, len(items))}
+	for _, item := range items {
+		response.Items = append(response.Items, toItemMessage(item))
+	}
+
+	return response, nil
+}
+
+func (s *itemServer) GetItem(ctx context.Context, req *todopb.GetItemRequest) (*todopb.GetItemResponse, error) {
+	c := callerFrom(ctx)
+	item, err := s.services.TodoItem.GetById(ctx, c.userId, c.workspaceId, int(req.Id))
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &todopb.GetItemResponse{Item: toItemMessage(item)}, nil
+}
+
+func (s *itemServer) UpdateItem(ctx context.Context, req *todopb.UpdateItemRequest) (*todopb.UpdateItemResponse, error) {
+	input := todo.UpdateItemInput{Title: req.Title, Description: req.Description, Done: req.Done}
+	if err := input.Validate(); err != nil {
+		return nil, statusError(err)
+	}
+
+	c := callerFrom(ctx)
+	if err := s.services.TodoItem.Update(ctx, c.userId, c.workspaceId, int(req.Id), input); err != nil {
+		return nil, statusError(err)
+	}
+
+	return &todopb.UpdateItemResponse{}, nil
+}
+
+func (s *itemServer) DeleteItem(ctx context.Context, req *todopb.DeleteItemRequest) (*todopb.DeleteItemResponse, error) {
+	c := callerFrom(ctx)
+	if err := s.services.TodoItem.Delete(ctx, c.userId, c.workspaceId, int(req.Id)); err != nil {
+		return nil, statusError(err)
+	}
+
+	return &todopb.DeleteItemResponse{}, nil
+}
@@ -0,0 +1,84 @@
+package rpc
+
+import (
+	"context"
+
+	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/rpc/todopb"
+	"akhmet.com/rest-api/pkg/service"
+)
+
+type listServer struct {
+	todopb.UnimplementedTodoListServiceServer
+	services *service.Service
+}
+
+func toListMessage(list todo.TodoList) *todopb.TodoList {
+	return &todopb.TodoList{
+		Id:          int64(list.Id),
+		WorkspaceId: int64(list.WorkspaceId),
+		Title:       list.Title,
+		Description: list.Description,
+	}
+}
+
+func (s *listServer) CreateList(ctx context.Context, req *todopb.CreateListRequest) (*todopb.CreateListResponse, error) {
+	c := callerFrom(ctx)
+	id, err := s.services.TodoList.Create(ctx, c.userId, c.workspaceId, todo.TodoList{
+		Title:       req.Title,
+		Description: req.Description,
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &todopb.CreateListResponse{Id: int64(id)}, nil
+}
+
+func (s *listServer) ListLists(ctx context.Context, req *todopb.ListListsRequest) (*todopb.ListListsResponse, error) {
+	c := callerFrom(ctx)
+	lists, err := s.services.TodoList.GetAll(ctx, c.userId, c.workspaceId)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	response := &todopb.ListListsResponse{Lists: make([]*todopb.TodoList, 0, len(lists))}
+	for _, list := range lists {
+		response.Lists = append(response.Lists, toListMessage(list))
+	}
+
+	return response, nil
+}
+
+func (s *listServer) GetList(ctx context.Context, req *todopb.GetListRequest) (*todopb.GetListResponse, error) {
+	c := callerFrom(ctx)
+	list, err := s.services.TodoList.GetById(ctx, c.userId, c.workspaceId, int(req.Id))
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &todopb.GetListResponse{List: toListMessage(list)}, nil
+}
+
+func (s *listServer) UpdateList(ctx context.Context, req *todopb.UpdateListRequest) (*todopb.UpdateListResponse, error) {
+	input := todo.UpdateListInput{Title: req.Title, Description: req.Description}
+	if err := input.Validate(); err != nil {
+		return nil, statusError(err)
+	}
+
+	c := callerFrom(ctx)
+	if err := s.services.TodoList.Update(ctx, c.userId, c.workspaceId, int(req.Id), input); err != nil {
+		return nil, statusError(err)
+	}
+
+	return &todopb.UpdateListResponse{}, nil
+}
+
+func (s *listServer) DeleteList(ctx context.Context, req *todopb.DeleteListRequest) (*todopb.DeleteListResponse, error) {
+	c := callerFrom(ctx)
+	if err := s.services.TodoList.Delete(ctx, c.userId, c.workspaceId, int(req.Id)); err != nil {
+		return nil, statusError(err)
+	}
+
+	return &todopb.DeleteListResponse{}, nil
+}
@@ -0,0 +1,79 @@
+package rpc
+
+import (
+	"context"
+	"time"
+
+	"akhmet.com/rest-api/pkg/logging"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const requestIdMetadata = "x-request-id"
+
+type accessKey struct{}
+
+// access collects what inner interceptors learn about the caller so the
+// access log entry written by logRequests can include it.
+type access struct {
+	userId int
+}
+
+// logRequests is the grpc counterpart of the REST request logging
+// middleware: it assigns the request id, returns it in the response
+// header and writes one access log entry per call.
+func logRequests(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	requestId := logging.RequestID(first(md, requestIdMetadata))
+	grpc.SetHeader(ctx, metadata.Pairs(requestIdMetadata, requestId))
+
+	fields := logrus.Fields{"request_id": requestId}
+	for key, value := range logging.TraceFields(ctx) {
+		fields[key] = value
+	}
+	ctx = logging.WithFields(ctx, fields)
+
+	a := &access{}
+	ctx = context.WithValue(ctx, accessKey{}, a)
+
+	resp, err := handler(ctx, req)
+
+	code := status.Code(err)
+	entry := logging.FromContext(ctx).WithFields(logrus.Fields{
+		"method":     info.FullMethod,
+		"grpc_code":  code.String(),
+		"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+	})
+	if a.userId != 0 {
+		entry = entry.WithField("user_id", a.userId)
+	}
+
+	level := logrus.InfoLevel
+	switch code {
+	case codes.OK:
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		level = logrus.ErrorLevel
+		entry = entry.WithField("error", status.Convert(err).Message())
+	default:
+		level = logrus.WarnLevel
+		entry = entry.WithField("error", status.Convert(err).Message())
+	}
+	entry.Log(level, "request completed")
+
+	return resp, err
+}
+
+// identified records the authenticated caller in the request logger and
+// the access log.
+func identified(ctx context.Context, userId, workspaceId int) context.Context {
+	if a, ok := ctx.Value(accessKey{}).(*access); ok {
+		a.userId = userId
+	}
+
+	return logging.WithFields(ctx, logrus.Fields{"user_id": userId, "workspace_id": workspaceId})
+}
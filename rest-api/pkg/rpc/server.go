@@ -0,0 +1,30 @@
+// Package rpc serves the todo API over gRPC. Handlers delegate to the same
+// services as the REST API; the generated code lives in todopb and is
+// rebuilt with go generate.
+package rpc
+
+//go:generate sh -c "cd ../.. && buf generate proto"
+
+import (
+	"akhmet.com/rest-api/pkg/rpc/todopb"
+	"akhmet.com/rest-api/pkg/service"
+	"google.golang.org/grpc"
+)
+
+type Server struct {
+	services *service.Service
+}
+
+// NewServer returns a grpc server with the authorization, list and item
+// services registered behind the tracing, logging and auth interceptors.
+func NewServer(services *service.Service, opts ...grpc.ServerOption) *grpc.Server {
+	s := &Server{services: services}
+
+	opts = append(opts, grpc.ChainUnaryInterceptor(traceRequests, logRequests, s.authenticate))
+	server := grpc.NewServer(opts...)
+	todopb.RegisterAuthorizationServiceServer(server, &authServer{services: services})
+	todopb.RegisterTodoListServiceServer(server, &listServer{services: services})
+	todopb.RegisterTodoItemServiceServer(server, &itemServer{services: services})
+
+	return server
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"akhmet.com/rest-api/pkg/repository"
+	"akhmet.com/rest-api/pkg/tracing"
+	"akhmet.com/rest-api"
+)
+
+const (
+	defaultUsersLimit = 50
+	maxUsersLimit     = 500
+)
+
+type AdminService struct {
+	repo     repository.Admin
+	authRepo repository.Authorization
+}
+
+func NewAdminService(repo repository.Admin, authRepo repository.Authorization) *AdminService {
+	return &AdminService{repo: repo, authRepo: authRepo}
+}
+
+func (s *AdminService) GetUsers(ctx context.Context, search string, limit, offset int) ([]todo.UserAccount, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.GetUsers")
+	defer span.End()
+
+	if limit <= 0 {
+		limit = defaultUsersLimit
+	}
+	if limit > maxUsersLimit {
+		limit = maxUsersLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	return s.repo.GetUsers(ctx, search, limit, offset)
+}
+
+func (s *AdminService) SetDisabled(ctx context.Context, userId int, disabled bool) error {
+	ctx, span := tracing.Start(ctx, "AdminService.SetDisabled")
+	defer span.End()
+
+	return s.repo.SetDisabled(ctx, userId, disabled)
+}
+
+func (s *AdminService) RequirePasswordReset(ctx context.Context, userId int) error {
+	ctx, span := tracing.Start(ctx, "AdminService.RequirePasswordReset")
+	defer span.End()
+
+	return s.repo.RequirePasswordReset(ctx, userId)
+}
+
+func (s *AdminService) GetUsage(ctx context.Context) ([]todo.UserUsage, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.GetUsage")
+	defer span.End()
+
+	return s.repo.GetUsage(ctx)
+}
+
+func (s *AdminService) TransferList(ctx context.Context, listId, userId int) error {
+	ctx, span := tracing.Start(ctx, "AdminService.TransferList")
+	defer span.End()
+
+	if _, err := s.authRepo.GetUserById(ctx, userId); err != nil {
+		return err
+	}
+
+	return s.repo.TransferList(ctx, listId, userId)
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/metrics"
+	"akhmet.com/rest-api/pkg/repository"
+	"akhmet.com/rest-api/pkg/tracing"
+)
+
+const apiKeyPrefix = "todo_"
+
+var (
+	ErrInvalidApiKey = errors.New("invalid api key")
+	ErrApiKeyExpired = errors.New("api key is expired")
+)
+
+type ApiKeyService struct {
+	repo  repository.ApiKey
+	users repository.Authorization
+}
+
+func NewApiKeyService(repo repository.ApiKey, users repository.Authorization) *ApiKeyService {
+	return &ApiKeyService{repo: repo, users: users}
+}
+
+// Create returns the stored key metadata together with the plain key. The
+// plain key is never persisted, so this is the only time it can be shown.
+func (s *ApiKeyService) Create(ctx context.Context, userId int, input todo.CreateApiKeyInput) (todo.ApiKey, string, error) {
+	ctx, span := tracing.Start(ctx, "ApiKeyService.Create")
+	defer span.End()
+
+	if err := input.Validate(); err != nil {
+		return todo.ApiKey{}, "", err
+	}
+
+	prefix, err := randomHex(4)
+	if err != nil {
+		return todo.ApiKey{}, "", err
+	}
+
+	secret, err := randomHex(24)
+	if err != nil {
+		return todo.ApiKey{}, "", err
+	}
+
+	key := todo.ApiKey{
+		UserId:    userId,
+		Name:      input.Name,
+		Prefix:    apiKeyPrefix + prefix,
+		Scopes:    input.Scopes,
+		ExpiresAt: input.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+	plain := key.Prefix + "_" + secret
+
+	key.Id, err = s.repo.Create(ctx, userId, key, hashApiKey(plain))
+	if err != nil {
+		return todo.ApiKey{}, "", err
+	}
+
+	return key, plain, nil
+}
+
+func (s *ApiKeyService) GetAll(ctx context.Context, userId int) ([]todo.ApiKey, error) {
+	ctx, span := tracing.Start(ctx, "ApiKeyService.GetAll")
+	defer span.End()
+
+	return s.repo.GetAll(ctx, userId)
+}
+
+func (s *ApiKeyService) Delete(ctx context.Context, userId, keyId int) error {
+	ctx, span := tracing.Start(ctx, "ApiKeyService.Delete")
+	defer span.End()
+
+	return s.repo.Delete(ctx, userId, keyId)
+}
+
+func (s *ApiKeyService) IsApiKey(token string) bool {
+	return strings.HasPrefix(token, apiKeyPrefix)
+}
+
+func (s *ApiKeyService) ParseKey(ctx context.Context, plain string) (key todo.ApiKey, err error) {
+	ctx, span := tracing.Start(ctx, "ApiKeyService.ParseKey")
+	defer span.End()
+	defer func() { metrics.AuthAttempt(metrics.AuthApiKey, err) }()
+
+	key, err = s.repo.GetByHash(ctx, hashApiKey(plain))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return todo.ApiKey{}, ErrInvalidApiKey
+		}
+		return todo.ApiKey{}, err
+	}
+
+	if key.ExpiresAt != nil && key.ExpiresAt.Before(time.Now()) {
+		return todo.ApiKey{}, ErrApiKeyExpired
+	}
+
+	if _, err := checkActive(ctx, s.users, key.UserId); err != nil {
+		return todo.ApiKey{}, err
+	}
+
+	if err := s.repo.UpdateLastUsed(ctx, key.Id); err != nil {
+		return todo.ApiKey{}, err
+	}
+
+	return key, nil
+}
+
+func hashApiKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/repository"
+)
+
+type fakeApiKeyRepo struct {
+	repository.ApiKey
+	key todo.ApiKey
+}
+
+func (r *fakeApiKeyRepo) GetByHash(ctx context.Context, keyHash string) (todo.ApiKey, error) {
+	return r.key, nil
+}
+
+func (r *fakeApiKeyRepo) UpdateLastUsed(ctx context.Context, keyId int) error {
+	return nil
+}
+
+func TestParseKeyChecksOwner(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		user todo.User
+		want error
+	}{
+		{"active", todo.User{Id: 1}, nil},
+		{"disabled", todo.User{Id: 1, Disabled: true}, ErrUserDisabled},
+		{"password reset", todo.User{Id: 1, PasswordResetRequired: true}, ErrPasswordResetRequired},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := &fakeApiKeyRepo{key: todo.ApiKey{Id: 7, UserId: 1}}
+			s := NewApiKeyService(keys, &fakeTOTPRepo{user: tt.user})
+
+			if _, err := s.ParseKey(context.Background(), apiKeyPrefix+"secret"); err != tt.want {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -1,79 +1,224 @@
 package service
 
 import (
+	"context"
 	"crypto/sha1"
 	"fmt"
 	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/metrics"
 	"akhmet.com/rest-api/pkg/repository"
+	"akhmet.com/rest-api/pkg/tracing"
+	"akhmet.com/rest-api/pkg/validate"
 	"github.com/dgrijalva/jwt-go"
 	"time"
 	"errors"
 )
 
 const (
-	salt = "agawreg416argarg"
-	signingKey = "adfgarfg46arg"
-	tokenTTL = 12 * time.Hour
+	DefaultTokenTTL = 12 * time.Hour
+	accessTokenType = "access"
+)
+
+var (
+	ErrUserDisabled          = errors.New("user is disabled")
+	ErrPasswordResetRequired = errors.New("password reset required")
+	ErrTwoFactorRequired     = errors.New("two-factor code required")
 )
 
 type tokenClaims struct {
 	jwt.StandardClaims
-	UserId int `json:"user_id"`
+	UserId    int    `json:"user_id"`
+	TokenType string `json:"token_type,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	Role      string `json:"role,omitempty"`
+}
+
+// AuthConfig holds the password and token settings. A zero TokenTTL falls
+// back to DefaultTokenTTL.
+type AuthConfig struct {
+	PasswordSalt string
+	TokenTTL     time.Duration
 }
 
 type AuthService struct {
 	repo repository.Authorization
+	keys *KeySet
+	cfg  AuthConfig
 }
 
-func NewAuthService(repo repository.Authorization) *AuthService {
-	return &AuthService{repo: repo}
+func NewAuthService(repo repository.Authorization, keys *KeySet, cfg AuthConfig) *AuthService {
+	if cfg.TokenTTL <= 0 {
+		cfg.TokenTTL = DefaultTokenTTL
+	}
+
+	return &AuthService{repo: repo, keys: keys, cfg: cfg}
 }
 
-func (s *AuthService) CreateUser(user todo.User) (int, error) {
-	user.Password = generatePasswordHash(user.Password)
-	return s.repo.CreateUser(user)
+func (s *AuthService) CreateUser(ctx context.Context, user todo.User) (int, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.CreateUser")
+	defer span.End()
+
+	if err := validate.Struct(&user); err != nil {
+		return 0, err
+	}
+
+	user.Password = s.generatePasswordHash(user.Password)
+	return s.repo.CreateUser(ctx, user)
 }
 
-func (s *AuthService) GenerateToken(username, password string) (string, error) {
-	user, err := s.repo.GetUser(username, generatePasswordHash(password))
+func (s *AuthService) Authenticate(ctx context.Context, username, password string) (user todo.User, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Authenticate")
+	defer span.End()
+	defer func() { metrics.AuthAttempt(metrics.AuthPassword, err) }()
+
+	user, err = s.repo.GetUser(ctx, username, s.generatePasswordHash(password))
+	if err != nil {
+		return user, err
+	}
+
+	if user.Disabled {
+		return todo.User{}, ErrUserDisabled
+	}
+
+	if user.PasswordResetRequired {
+		return todo.User{}, ErrPasswordResetRequired
+	}
+
+	return user, nil
+}
+
+func (s *AuthService) GetUserById(ctx context.Context, userId int) (todo.User, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.GetUserById")
+	defer span.End()
+
+	return s.repo.GetUserById(ctx, userId)
+}
+
+// ChangePassword also clears a forced password reset, so accounts with
+// two-factor authentication need a TOTP or recovery code as well, the same
+// as for signing in.
+func (s *AuthService) ChangePassword(ctx context.Context, username, password, newPassword, code string) error {
+	ctx, span := tracing.Start(ctx, "AuthService.ChangePassword")
+	defer span.End()
+
+	user, err := s.repo.GetUser(ctx, username, s.generatePasswordHash(password))
+	if err != nil {
+		return err
+	}
+
+	if user.Disabled {
+		return ErrUserDisabled
+	}
+
+	if user.TOTPEnabled {
+		if err := s.verifySecondFactor(ctx, user.Id, code); err != nil {
+			return err
+		}
+	}
+
+	return s.repo.UpdatePassword(ctx, user.Id, s.generatePasswordHash(newPassword))
+}
+
+func (s *AuthService) GenerateToken(ctx context.Context, userId int, scopes todo.Scopes) (string, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.GenerateToken")
+	defer span.End()
+
+	if err := scopes.ValidateSubset(todo.AllScopes); err != nil {
+		return "", err
+	}
+
+	user, err := s.repo.GetUserById(ctx, userId)
 	if err != nil {
 		return "", err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &tokenClaims{
-		jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(tokenTTL).Unix(),
+	if user.Disabled {
+		return "", ErrUserDisabled
+	}
+
+	return s.keys.sign(&tokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(s.cfg.TokenTTL).Unix(),
 			IssuedAt: time.Now().Unix(),
 		},
-		user.Id,
+		UserId:    userId,
+		TokenType: accessTokenType,
+		Scope:     scopes.String(),
+		Role:      user.Role,
 	})
+}
 
-	return token.SignedString([]byte(signingKey))
+func (s *AuthService) verifySecondFactor(ctx context.Context, userId int, code string) error {
+	if code == "" {
+		return ErrTwoFactorRequired
+	}
+
+	user, err := s.repo.GetUserById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	return verifyCode(ctx, s.repo, user, code)
 }
 
-func generatePasswordHash(password string) string {
+func (s *AuthService) generatePasswordHash(password string) string {
 	hash := sha1.New()
 	hash.Write([]byte(password))
-	return fmt.Sprintf("%x", hash.Sum([]byte(salt)))
+	return fmt.Sprintf("%x", hash.Sum([]byte(s.cfg.PasswordSalt)))
 }
 
-func (s *AuthService) ParseToken(accessToken string) (int, error) {
-	token, err := jwt.ParseWithClaims(accessToken, &tokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("invalid signing method")
-		}
+func (s *AuthService) ParseToken(ctx context.Context, accessToken string) (principal todo.Principal, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.ParseToken")
+	defer span.End()
+	defer func() { metrics.AuthAttempt(metrics.AuthToken, err) }()
 
-		return []byte(signingKey), nil
-	})
+	claims, err := s.keys.parseTokenClaims(accessToken)
 	if err != nil {
-		return 0, err
+		return todo.Principal{}, err
 	}
 
-	claims, ok := token.Claims.(*tokenClaims)
-	if !ok {
-		return 0, errors.New("token claims are not of type *tokenClaims")
+	if claims.TokenType == challengeTokenType {
+		return todo.Principal{}, errors.New("two-factor challenge is not completed")
 	}
 
-	return claims.UserId, nil
+	if claims.TokenType != accessTokenType {
+		return todo.Principal{}, errors.New("token is not an access token")
+	}
+
+	user, err := checkActive(ctx, s.repo, claims.UserId)
+	if err != nil {
+		return todo.Principal{}, err
+	}
+
+	return todo.Principal{
+		UserId: claims.UserId,
+		Role:   user.Role,
+		Scopes: todo.ParseScopes(claims.Scope),
+	}, nil
+}
+
+// checkActive makes disabling an account or forcing a password reset take
+// effect on tokens and api keys that were issued before the change. The
+// returned user carries the current role, so that a role change applies to
+// tokens issued before it as well.
+func checkActive(ctx context.Context, repo repository.Authorization, userId int) (todo.User, error) {
+	user, err := repo.GetUserById(ctx, userId)
+	if err != nil {
+		return todo.User{}, err
+	}
+
+	if user.Disabled {
+		return todo.User{}, ErrUserDisabled
+	}
+
+	if user.PasswordResetRequired {
+		return todo.User{}, ErrPasswordResetRequired
+	}
+
+	return user, nil
+}
+
+func (s *AuthService) JWKS() JWKSet {
+	return s.keys.JWKS()
 }
 
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"akhmet.com/rest-api"
+)
+
+// fakePasswordRepo signs in the user of fakeTOTPRepo with any password and
+// records password changes.
+type fakePasswordRepo struct {
+	fakeTOTPRepo
+	changed bool
+}
+
+func (r *fakePasswordRepo) GetUser(ctx context.Context, username, password string) (todo.User, error) {
+	return todo.User{Id: r.user.Id, TOTPEnabled: r.user.TOTPEnabled, PasswordResetRequired: r.user.PasswordResetRequired}, nil
+}
+
+func (r *fakePasswordRepo) UpdatePassword(ctx context.Context, userId int, password string) error {
+	r.changed = true
+	return nil
+}
+
+func TestChangePasswordRequiresSecondFactor(t *testing.T) {
+	keys, err := NewEphemeralKeySet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &fakePasswordRepo{fakeTOTPRepo: fakeTOTPRepo{user: todo.User{
+		Id: 1, TOTPSecret: rfc6238Secret, TOTPEnabled: true, PasswordResetRequired: true,
+	}}}
+	s := NewAuthService(repo, keys, AuthConfig{})
+
+	if err := s.ChangePassword(context.Background(), "alice", "old", "new-password", ""); err != ErrTwoFactorRequired {
+		t.Fatalf("without code: got %v, want %v", err, ErrTwoFactorRequired)
+	}
+	if err := s.ChangePassword(context.Background(), "alice", "old", "new-password", "000000"); err != ErrInvalidTwoFactorCode {
+		t.Fatalf("wrong code: got %v, want %v", err, ErrInvalidTwoFactorCode)
+	}
+	if repo.changed {
+		t.Fatal("password changed without a valid code")
+	}
+
+	code, err := totpCode(rfc6238Secret, time.Now().Unix()/totpPeriod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ChangePassword(context.Background(), "alice", "old", "new-password", code); err != nil {
+		t.Fatalf("valid code: %v", err)
+	}
+	if !repo.changed {
+		t.Fatal("password not changed")
+	}
+}
+
+func TestChangePasswordCountsFailedCodes(t *testing.T) {
+	keys, err := NewEphemeralKeySet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &fakePasswordRepo{fakeTOTPRepo: fakeTOTPRepo{user: todo.User{
+		Id: 1, TOTPSecret: rfc6238Secret, TOTPEnabled: true,
+	}}}
+	s := NewAuthService(repo, keys, AuthConfig{})
+
+	for i := 0; i < maxTwoFactorAttempts; i++ {
+		s.ChangePassword(context.Background(), "alice", "old", "new-password", "not-a-code")
+	}
+
+	code, err := totpCode(rfc6238Secret, time.Now().Unix()/totpPeriod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ChangePassword(context.Background(), "alice", "old", "new-password", code); err != ErrTwoFactorLocked {
+		t.Fatalf("got %v, want %v", err, ErrTwoFactorLocked)
+	}
+	if repo.changed {
+		t.Fatal("password changed while two-factor verification is locked")
+	}
+}
+
+func TestParseTokenUsesCurrentRole(t *testing.T) {
+	keys, err := NewEphemeralKeySet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &fakeTOTPRepo{user: todo.User{Id: 1, Role: "admin"}}
+	s := NewAuthService(repo, keys, AuthConfig{})
+
+	token, err := s.GenerateToken(context.Background(), 1, todo.AllScopes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo.user.Role = "user"
+	principal, err := s.ParseToken(context.Background(), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principal.Role != "user" {
+		t.Errorf("role = %q, want the demoted role user", principal.Role)
+	}
+}
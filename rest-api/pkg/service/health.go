@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/repository"
+)
+
+// readyCheckTimeout bounds the database checks of a readiness probe, so a
+// hanging database fails the probe instead of timing it out.
+const readyCheckTimeout = 2 * time.Second
+
+type HealthService struct {
+	repo         repository.Health
+	build        todo.BuildInfo
+	startedAt    time.Time
+	shuttingDown int32
+}
+
+func NewHealthService(repo repository.Health, build todo.BuildInfo) *HealthService {
+	build.GoVersion = runtime.Version()
+	return &HealthService{repo: repo, build: build, startedAt: time.Now()}
+}
+
+// BeginShutdown makes readiness fail from now on, so the instance is taken
+// out of rotation while in-flight requests finish.
+func (s *HealthService) BeginShutdown() {
+	atomic.StoreInt32(&s.shuttingDown, 1)
+}
+
+func (s *HealthService) ShuttingDown() bool {
+	return atomic.LoadInt32(&s.shuttingDown) == 1
+}
+
+// Ready checks that the instance is not shutting down, the database
+// answers and its schema has the migrations this build needs. A newer
+// schema is fine: during a rolling deploy the new build migrates first and
+// the old instances keep serving until they are replaced.
+func (s *HealthService) Ready(ctx context.Context) todo.Readiness {
+	ctx, cancel := context.WithTimeout(ctx, readyCheckTimeout)
+	defer cancel()
+
+	checks := []todo.HealthCheck{
+		check("shutdown", func() error {
+			if s.ShuttingDown() {
+				return errors.New("shutting down")
+			}
+			return nil
+		}),
+		check("database", func() error {
+			return s.repo.Ping(ctx)
+		}),
+		check("migrations", func() error {
+			version, dirty, err := s.repo.GetSchemaVersion(ctx)
+			if err != nil {
+				return err
+			}
+			if dirty {
+				return fmt.Errorf("migration %d failed and left the schema dirty", version)
+			}
+			if version < repository.SchemaVersion {
+				return fmt.Errorf("schema is at version %d, expected at least %d", version, repository.SchemaVersion)
+			}
+			return nil
+		}),
+	}
+
+	readiness := todo.Readiness{Status: todo.HealthStatusOK, Checks: checks}
+	for _, c := range checks {
+		if c.Status != todo.HealthStatusOK {
+			readiness.Status = todo.HealthStatusUnavailable
+		}
+	}
+
+	return readiness
+}
+
+func (s *HealthService) Status(ctx context.Context) todo.ServiceStatus {
+	stats := s.repo.Stats()
+
+	return todo.ServiceStatus{
+		Build:                 s.build,
+		StartedAt:             s.startedAt,
+		UptimeSeconds:         time.Since(s.startedAt).Seconds(),
+		ShuttingDown:          s.ShuttingDown(),
+		ExpectedSchemaVersion: repository.SchemaVersion,
+		DBPool: todo.DBPoolStats{
+			MaxOpenConnections: stats.MaxOpenConnections,
+			OpenConnections:    stats.OpenConnections,
+			InUse:              stats.InUse,
+			Idle:               stats.Idle,
+			WaitCount:          stats.WaitCount,
+			WaitDurationMs:     float64(stats.WaitDuration.Microseconds()) / 1000,
+			MaxIdleClosed:      stats.MaxIdleClosed,
+			MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+		},
+		Readiness: s.Ready(ctx),
+	}
+}
+
+func check(name string, fn func() error) todo.HealthCheck {
+	start := time.Now()
+	err := fn()
+
+	result := todo.HealthCheck{
+		Name:      name,
+		Status:    todo.HealthStatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = todo.HealthStatusUnavailable
+		result.Error = err.Error()
+	}
+
+	return result
+}
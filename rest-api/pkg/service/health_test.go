@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/repository"
+)
+
+// fakeHealthRepo answers pings and reports a fixed schema version.
+type fakeHealthRepo struct {
+	repository.Health
+	version int
+	dirty   bool
+}
+
+func (r *fakeHealthRepo) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (r *fakeHealthRepo) GetSchemaVersion(ctx context.Context) (int, bool, error) {
+	return r.version, r.dirty, nil
+}
+
+func TestReadySchemaVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int
+		dirty   bool
+		status  string
+	}{
+		{"newer", repository.SchemaVersion + 1, false, todo.HealthStatusOK},
+		{"equal", repository.SchemaVersion, false, todo.HealthStatusOK},
+		{"older", repository.SchemaVersion - 1, false, todo.HealthStatusUnavailable},
+		{"dirty", repository.SchemaVersion, true, todo.HealthStatusUnavailable},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := NewHealthService(&fakeHealthRepo{version: test.version, dirty: test.dirty}, todo.BuildInfo{})
+
+			readiness := s.Ready(context.Background())
+			if readiness.Status != test.status {
+				t.Errorf("status = %q, want %q (checks %+v)", readiness.Status, test.status, readiness.Checks)
+			}
+		})
+	}
+}
+
+func TestReadyFailsWhileShuttingDown(t *testing.T) {
+	s := NewHealthService(&fakeHealthRepo{version: repository.SchemaVersion}, todo.BuildInfo{})
+	s.BeginShutdown()
+
+	if readiness := s.Ready(context.Background()); readiness.Status != todo.HealthStatusUnavailable {
+		t.Errorf("status = %q, want %q", readiness.Status, todo.HealthStatusUnavailable)
+	}
+}
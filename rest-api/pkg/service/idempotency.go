@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/repository"
+	"akhmet.com/rest-api/pkg/tracing"
+)
+
+const idempotencyTTL = 24 * time.Hour
+
+var (
+	ErrIdempotencyKeyReused     = errors.New("idempotency key was already used for a different request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still in progress")
+)
+
+type IdempotencyService struct {
+	repo repository.Idempotency
+}
+
+func NewIdempotencyService(repo repository.Idempotency) *IdempotencyService {
+	return &IdempotencyService{repo: repo}
+}
+
+// Begin reserves key for a request with the given fingerprint. It returns
+// nil when the request should run, or the stored response to replay when
+// the same request was already completed.
+func (s *IdempotencyService) Begin(ctx context.Context, userId int, key, fingerprint string) (*todo.IdempotencyRecord, error) {
+	ctx, span := tracing.Start(ctx, "IdempotencyService.Begin")
+	defer span.End()
+
+	record, reserved, err := s.repo.Reserve(ctx, userId, key, fingerprint, time.Now().Add(-idempotencyTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	if reserved {
+		return nil, nil
+	}
+
+	if record.Fingerprint != fingerprint {
+		return nil, ErrIdempotencyKeyReused
+	}
+
+	if record.StatusCode == 0 {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+
+	return &record, nil
+}
+
+func (s *IdempotencyService) Complete(ctx context.Context, userId int, key string, statusCode int, contentType string, body []byte) error {
+	ctx, span := tracing.Start(ctx, "IdempotencyService.Complete")
+	defer span.End()
+
+	return s.repo.Complete(ctx, userId, key, statusCode, contentType, body)
+}
+
+// Abort releases the key so the request can be retried, used when the
+// request failed with a server error.
+func (s *IdempotencyService) Abort(ctx context.Context, userId int, key string) error {
+	ctx, span := tracing.Start(ctx, "IdempotencyService.Abort")
+	defer span.End()
+
+	return s.repo.Release(ctx, userId, key)
+}
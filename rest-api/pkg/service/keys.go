@@ -0,0 +1,294 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	algorithmRS256 = "RS256"
+	algorithmEdDSA = "EdDSA"
+)
+
+// KeyConfig describes one signing or verification key. The PEM material is
+// read from a file or, when the *Env field is set, from that environment
+// variable. Keys without a private part can only verify tokens, which is how
+// retired keys stay valid until the tokens they signed expire.
+type KeyConfig struct {
+	Id             string
+	Algorithm      string
+	PrivateKeyFile string
+	PrivateKeyEnv  string
+	PublicKeyFile  string
+	PublicKeyEnv   string
+}
+
+type signingKey struct {
+	id         string
+	method     jwt.SigningMethod
+	privateKey crypto.PrivateKey
+	publicKey  crypto.PublicKey
+}
+
+type KeySet struct {
+	active *signingKey
+	keys   map[string]*signingKey
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+func NewKeySet(configs []KeyConfig, activeId string) (*KeySet, error) {
+	set := &KeySet{keys: make(map[string]*signingKey)}
+
+	for _, cfg := range configs {
+		key, err := loadKey(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", cfg.Id, err)
+		}
+
+		if _, ok := set.keys[key.id]; ok {
+			return nil, fmt.Errorf("key %q is configured twice", key.id)
+		}
+		set.keys[key.id] = key
+	}
+
+	active, ok := set.keys[activeId]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q is not configured", activeId)
+	}
+
+	if active.privateKey == nil {
+		return nil, fmt.Errorf("signing key %q has no private key", activeId)
+	}
+	set.active = active
+
+	return set, nil
+}
+
+// NewEphemeralKeySet generates a throwaway Ed25519 key. Tokens signed with it
+// do not survive a restart, so it is only meant for local development.
+func NewEphemeralKeySet() (*KeySet, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{
+		id:         "ephemeral",
+		method:     SigningMethodEdDSA,
+		privateKey: privateKey,
+		publicKey:  publicKey,
+	}
+
+	return &KeySet{
+		active: key,
+		keys:   map[string]*signingKey{key.id: key},
+	}, nil
+}
+
+func (s *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.active.method, claims)
+	token.Header["kid"] = s.active.id
+
+	return token.SignedString(s.active.privateKey)
+}
+
+func (s *KeySet) parse(tokenString string, claims jwt.Claims) error {
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			return nil, errors.New("token has no key id")
+		}
+
+		key, ok := s.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, errors.New("invalid signing method")
+		}
+
+		return key.publicKey, nil
+	})
+
+	return err
+}
+
+func (s *KeySet) parseTokenClaims(tokenString string) (*tokenClaims, error) {
+	claims := &tokenClaims{}
+	if err := s.parse(tokenString, claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (s *KeySet) JWKS() JWKSet {
+	set := JWKSet{Keys: make([]JWK, 0, len(s.keys))}
+
+	for _, key := range s.keys {
+		jwk := JWK{Kid: key.id, Use: "sig", Alg: key.method.Alg()}
+
+		switch pub := key.publicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+
+	return set
+}
+
+func loadKey(cfg KeyConfig) (*signingKey, error) {
+	if cfg.Id == "" {
+		return nil, errors.New("key id is empty")
+	}
+
+	key := &signingKey{id: cfg.Id}
+
+	switch cfg.Algorithm {
+	case algorithmRS256:
+		key.method = jwt.SigningMethodRS256
+	case algorithmEdDSA:
+		key.method = SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", cfg.Algorithm)
+	}
+
+	privatePEM, err := readPEM(cfg.PrivateKeyFile, cfg.PrivateKeyEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	if privatePEM != nil {
+		privateKey, publicKey, err := parsePrivateKey(privatePEM)
+		if err != nil {
+			return nil, err
+		}
+		key.privateKey = privateKey
+		key.publicKey = publicKey
+	} else {
+		publicPEM, err := readPEM(cfg.PublicKeyFile, cfg.PublicKeyEnv)
+		if err != nil {
+			return nil, err
+		}
+
+		if publicPEM == nil {
+			return nil, errors.New("neither private nor public key is configured")
+		}
+
+		key.publicKey, err = parsePublicKey(publicPEM)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch key.publicKey.(type) {
+	case *rsa.PublicKey:
+		if key.method != jwt.SigningMethodRS256 {
+			return nil, errors.New("RSA key configured with non-RSA algorithm")
+		}
+	case ed25519.PublicKey:
+		if key.method != SigningMethodEdDSA {
+			return nil, errors.New("Ed25519 key configured with non-EdDSA algorithm")
+		}
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+
+	return key, nil
+}
+
+func readPEM(file, env string) ([]byte, error) {
+	var data []byte
+
+	switch {
+	case env != "":
+		value := os.Getenv(env)
+		if value == "" {
+			return nil, fmt.Errorf("environment variable %s is empty", env)
+		}
+		data = []byte(value)
+	case file != "":
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		data = content
+	default:
+		return nil, nil
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	return block.Bytes, nil
+}
+
+func parsePrivateKey(der []byte) (crypto.PrivateKey, crypto.PublicKey, error) {
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, &key.PublicKey, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, &k.PublicKey, nil
+	case ed25519.PrivateKey:
+		return k, k.Public(), nil
+	default:
+		return nil, nil, errors.New("unsupported private key type")
+	}
+}
+
+func parsePublicKey(der []byte) (crypto.PublicKey, error) {
+	if key, err := x509.ParsePKCS1PublicKey(der); err == nil {
+		return key, nil
+	}
+
+	return x509.ParsePKIXPublicKey(der)
+}
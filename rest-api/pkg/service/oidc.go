@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/metrics"
+	"akhmet.com/rest-api/pkg/oidc"
+	"akhmet.com/rest-api/pkg/repository"
+	"akhmet.com/rest-api/pkg/tracing"
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	oidcLoginTokenType = "oidc_login"
+	oidcLoginTTL       = 10 * time.Minute
+)
+
+var (
+	ErrInvalidOIDCState = errors.New("invalid oidc login state")
+	ErrIdentityLinked   = errors.New("this identity is linked to another account")
+)
+
+// oidcLoginClaims carry the per-login secrets between the redirect to the
+// provider and the callback. They are signed with the token keys and kept in
+// a cookie, so no server-side session storage is needed.
+type oidcLoginClaims struct {
+	jwt.StandardClaims
+	TokenType string `json:"token_type"`
+	State     string `json:"state"`
+	Nonce     string `json:"nonce"`
+	Verifier  string `json:"verifier"`
+	// LinkUserId is set when a signed-in user links the identity to their
+	// account instead of signing in with it.
+	LinkUserId int `json:"link_user_id,omitempty"`
+}
+
+type OIDCService struct {
+	provider *oidc.Provider
+	repo     repository.Identity
+	keys     *KeySet
+}
+
+func NewOIDCService(provider *oidc.Provider, repo repository.Identity, keys *KeySet) *OIDCService {
+	return &OIDCService{provider: provider, repo: repo, keys: keys}
+}
+
+// LoginURL returns the provider URL to redirect to and the signed login
+// state to keep in a cookie until the callback.
+func (s *OIDCService) LoginURL() (string, string, error) {
+	return s.authURL(0)
+}
+
+// LinkURL works like LoginURL, but the callback links the identity to the
+// signed-in user. This is the only way an existing account gets linked.
+func (s *OIDCService) LinkURL(userId int) (string, string, error) {
+	return s.authURL(userId)
+}
+
+func (s *OIDCService) authURL(linkUserId int) (string, string, error) {
+	state, err := randomString(24)
+	if err != nil {
+		return "", "", err
+	}
+
+	nonce, err := randomString(24)
+	if err != nil {
+		return "", "", err
+	}
+
+	verifier, err := randomString(48)
+	if err != nil {
+		return "", "", err
+	}
+
+	loginState, err := s.keys.sign(&oidcLoginClaims{
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(oidcLoginTTL).Unix(),
+			IssuedAt:  time.Now().Unix(),
+		},
+		TokenType:  oidcLoginTokenType,
+		State:      state,
+		Nonce:      nonce,
+		Verifier:   verifier,
+		LinkUserId: linkUserId,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return s.provider.AuthCodeURL(state, nonce, verifier), loginState, nil
+}
+
+func (s *OIDCService) Callback(ctx context.Context, code, state, loginState string) (userId int, err error) {
+	ctx, span := tracing.Start(ctx, "OIDCService.Callback")
+	defer span.End()
+	defer func() { metrics.AuthAttempt(metrics.AuthOIDC, err) }()
+
+	var login oidcLoginClaims
+	if err := s.keys.parse(loginState, &login); err != nil {
+		return 0, ErrInvalidOIDCState
+	}
+
+	if login.TokenType != oidcLoginTokenType || login.State == "" || login.State != state {
+		return 0, ErrInvalidOIDCState
+	}
+
+	claims, err := s.provider.Exchange(ctx, code, login.Verifier)
+	if err != nil {
+		return 0, err
+	}
+
+	if claims.Nonce != login.Nonce {
+		return 0, errors.New("id token nonce mismatch")
+	}
+
+	if login.LinkUserId != 0 {
+		return s.linkUser(ctx, login.LinkUserId, claims)
+	}
+
+	return s.resolveUser(ctx, claims)
+}
+
+func (s *OIDCService) linkUser(ctx context.Context, userId int, claims *oidc.Claims) (int, error) {
+	issuer := s.provider.Issuer()
+
+	user, err := s.repo.GetUserByIdentity(ctx, issuer, claims.Subject)
+	if err == nil {
+		if user.Id != userId {
+			return 0, ErrIdentityLinked
+		}
+		return userId, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	return userId, s.repo.LinkIdentity(ctx, userId, issuer, claims.Subject)
+}
+
+// resolveUser returns the user linked to the provider subject, or creates a
+// new password-less account on first login. Existing local accounts are
+// never linked by email or username: whoever controls that address at the
+// provider would take the account over.
+func (s *OIDCService) resolveUser(ctx context.Context, claims *oidc.Claims) (int, error) {
+	issuer := s.provider.Issuer()
+
+	user, err := s.repo.GetUserByIdentity(ctx, issuer, claims.Subject)
+	if err == nil {
+		return user.Id, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	username := claims.PreferredUsername
+	if username == "" {
+		username = claims.Email
+	}
+	if username == "" {
+		username = claims.Subject
+	}
+
+	if _, err := s.repo.GetUserByUsername(ctx, username); err == nil {
+		username = fmt.Sprintf("%s-%s", username, shortSubject(claims.Subject))
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = username
+	}
+
+	return s.repo.CreateUserWithIdentity(ctx, todo.User{Name: name, Username: username}, issuer, claims.Subject)
+}
+
+func shortSubject(subject string) string {
+	subject = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, subject)
+
+	if len(subject) > 8 {
+		return subject[len(subject)-8:]
+	}
+
+	return subject
+}
+
+func randomString(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,339 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/oidc"
+	"akhmet.com/rest-api/pkg/repository"
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	stubClientId    = "todo-app"
+	stubRedirectURL = "http://localhost:8008/auth/oidc/callback"
+	stubKeyId       = "stub-key"
+)
+
+// stubProvider is a local OpenID provider serving discovery, JWKS and the
+// token endpoint. authorize stands in for the browser round trip: it
+// records the PKCE challenge and nonce of an authorization URL and returns
+// a code for the given subject.
+type stubProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]stubGrant
+}
+
+type stubGrant struct {
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+func newStubProvider(t *testing.T) *stubProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &stubProvider{t: t, key: key, codes: map[string]stubGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": stubKeyId,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", p.token)
+
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+
+	return p
+}
+
+func (p *stubProvider) provider() *oidc.Provider {
+	provider, err := oidc.NewProvider(context.Background(), oidc.Config{
+		Issuer:      p.server.URL,
+		ClientId:    stubClientId,
+		RedirectURL: stubRedirectURL,
+	})
+	if err != nil {
+		p.t.Fatal(err)
+	}
+
+	return provider
+}
+
+func (p *stubProvider) authorize(authURL string, claims jwt.MapClaims) (code, state string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	query := u.Query()
+
+	if query.Get("code_challenge_method") != "S256" || query.Get("redirect_uri") != stubRedirectURL {
+		p.t.Fatalf("unexpected authorization request %s", authURL)
+	}
+
+	code = "code-" + query.Get("state")
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.codes[code] = stubGrant{challenge: query.Get("code_challenge"), nonce: query.Get("nonce"), claims: claims}
+
+	return code, query.Get("state")
+}
+
+func (p *stubProvider) token(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	grant, ok := p.codes[r.FormValue("code")]
+	delete(p.codes, r.FormValue("code"))
+	p.mu.Unlock()
+
+	if !ok || oidc.CodeChallenge(r.FormValue("code_verifier")) != grant.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   p.server.URL,
+		"aud":   stubClientId,
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": grant.nonce,
+	}
+	for k, v := range grant.claims {
+		claims[k] = v
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = stubKeyId
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"id_token": idToken})
+}
+
+// fakeIdentityRepo keeps users and linked identities in memory.
+type fakeIdentityRepo struct {
+	repository.Identity
+	users      map[int]todo.User
+	identities map[string]int
+}
+
+func newFakeIdentityRepo(users ...todo.User) *fakeIdentityRepo {
+	r := &fakeIdentityRepo{users: map[int]todo.User{}, identities: map[string]int{}}
+	for _, user := range users {
+		r.users[user.Id] = user
+	}
+	return r
+}
+
+func (r *fakeIdentityRepo) GetUserByIdentity(ctx context.Context, issuer, subject string) (todo.User, error) {
+	id, ok := r.identities[issuer+"|"+subject]
+	if !ok {
+		return todo.User{}, sql.ErrNoRows
+	}
+	return r.users[id], nil
+}
+
+func (r *fakeIdentityRepo) GetUserByUsername(ctx context.Context, username string) (todo.User, error) {
+	for _, user := range r.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return todo.User{}, sql.ErrNoRows
+}
+
+func (r *fakeIdentityRepo) LinkIdentity(ctx context.Context, userId int, issuer, subject string) error {
+	r.identities[issuer+"|"+subject] = userId
+	return nil
+}
+
+func (r *fakeIdentityRepo) CreateUserWithIdentity(ctx context.Context, user todo.User, issuer, subject string) (int, error) {
+	user.Id = len(r.users) + 1
+	r.users[user.Id] = user
+	r.identities[issuer+"|"+subject] = user.Id
+	return user.Id, nil
+}
+
+func newTestOIDCService(t *testing.T, repo *fakeIdentityRepo) (*OIDCService, *stubProvider) {
+	keys, err := NewEphemeralKeySet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stub := newStubProvider(t)
+	return NewOIDCService(stub.provider(), repo, keys), stub
+}
+
+func login(t *testing.T, s *OIDCService, stub *stubProvider, claims jwt.MapClaims) (int, error) {
+	authURL, loginState, err := s.LoginURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, state := stub.authorize(authURL, claims)
+	return s.Callback(context.Background(), code, state, loginState)
+}
+
+func TestOIDCCallbackCreatesUserOnceBySubject(t *testing.T) {
+	repo := newFakeIdentityRepo()
+	s, stub := newTestOIDCService(t, repo)
+
+	claims := jwt.MapClaims{"sub": "alice-sub", "preferred_username": "alice", "name": "Alice"}
+
+	first, err := login(t, s, stub, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := login(t, s, stub, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first != second {
+		t.Fatalf("second login resolved to user %d, want %d", second, first)
+	}
+	if user := repo.users[first]; user.Username != "alice" || user.Name != "Alice" {
+		t.Fatalf("unexpected user %+v", user)
+	}
+}
+
+func TestOIDCCallbackDoesNotLinkByEmail(t *testing.T) {
+	local := todo.User{Id: 1, Name: "Bob", Username: "bob@example.com"}
+	repo := newFakeIdentityRepo(local)
+	s, stub := newTestOIDCService(t, repo)
+
+	userId, err := login(t, s, stub, jwt.MapClaims{
+		"sub":            "attacker-sub",
+		"email":          "bob@example.com",
+		"email_verified": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if userId == local.Id {
+		t.Fatal("identity was linked to the local account with the same email")
+	}
+	if username := repo.users[userId].Username; username == local.Username {
+		t.Fatalf("new account reuses username %q", username)
+	}
+}
+
+func TestOIDCLinkURLLinksSignedInUser(t *testing.T) {
+	repo := newFakeIdentityRepo(todo.User{Id: 1, Username: "carol"}, todo.User{Id: 2, Username: "dave"})
+	s, stub := newTestOIDCService(t, repo)
+
+	link := func(userId int) (int, error) {
+		authURL, loginState, err := s.LinkURL(userId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code, state := stub.authorize(authURL, jwt.MapClaims{"sub": "carol-sub"})
+		return s.Callback(context.Background(), code, state, loginState)
+	}
+
+	if userId, err := link(1); err != nil || userId != 1 {
+		t.Fatalf("link: got %d, %v", userId, err)
+	}
+	if userId, err := login(t, s, stub, jwt.MapClaims{"sub": "carol-sub"}); err != nil || userId != 1 {
+		t.Fatalf("login after link: got %d, %v", userId, err)
+	}
+	if _, err := link(2); err != ErrIdentityLinked {
+		t.Fatalf("linking to a second account: got %v, want %v", err, ErrIdentityLinked)
+	}
+}
+
+func TestOIDCCallbackRejectsForgedState(t *testing.T) {
+	s, stub := newTestOIDCService(t, newFakeIdentityRepo())
+
+	authURL, loginState, err := s.LoginURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := stub.authorize(authURL, jwt.MapClaims{"sub": "eve-sub"})
+
+	if _, err := s.Callback(context.Background(), code, "forged", loginState); err != ErrInvalidOIDCState {
+		t.Fatalf("got %v, want %v", err, ErrInvalidOIDCState)
+	}
+
+	_, otherState, err := s.LoginURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Callback(context.Background(), code, stateOf(t, authURL), otherState); err != ErrInvalidOIDCState {
+		t.Fatalf("state cookie of another login: got %v, want %v", err, ErrInvalidOIDCState)
+	}
+}
+
+func TestOIDCCallbackRejectsWrongVerifier(t *testing.T) {
+	s, stub := newTestOIDCService(t, newFakeIdentityRepo())
+
+	authURL, _, err := s.LoginURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state := stub.authorize(authURL, jwt.MapClaims{"sub": "mallory-sub"})
+
+	// A login state with the same state value but a different verifier, as
+	// an attacker who intercepted the code would have.
+	forged, err := s.keys.sign(&oidcLoginClaims{
+		StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Minute).Unix()},
+		TokenType:      oidcLoginTokenType,
+		State:          state,
+		Verifier:       "not-the-verifier",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Callback(context.Background(), code, state, forged); err == nil {
+		t.Fatal("code redeemed without the matching PKCE verifier")
+	}
+}
+
+func stateOf(t *testing.T, authURL string) string {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query().Get("state")
+}
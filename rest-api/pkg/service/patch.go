@@ -0,0 +1,48 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"akhmet.com/rest-api/pkg/jsonpatch"
+)
+
+// ErrInvalidPatchResult is returned when a patch applies cleanly but the
+// resulting document is not a valid resource.
+var ErrInvalidPatchResult = errors.New("patched document is invalid")
+
+// applyPatch runs patch against the JSON form of current and decodes the
+// outcome into result. Members listed in required must still be present
+// and non-null, and members not known to result are rejected.
+func applyPatch(current interface{}, patch jsonpatch.Patch, result interface{}, required ...string) error {
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+
+	patched, err := patch.Apply(doc)
+	if err != nil {
+		return err
+	}
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(patched, &members); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidPatchResult, "document must be an object")
+	}
+
+	for _, name := range required {
+		if value, ok := members[name]; !ok || string(value) == "null" {
+			return fmt.Errorf("%w: %s is required", ErrInvalidPatchResult, name)
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(result); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidPatchResult, err.Error())
+	}
+
+	return nil
+}
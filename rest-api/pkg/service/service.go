@@ -1,42 +1,129 @@
 package service
 
 import (
+	"context"
+
+	"akhmet.com/rest-api/pkg/jsonpatch"
+	"akhmet.com/rest-api/pkg/oidc"
 	"akhmet.com/rest-api/pkg/repository"
 	"akhmet.com/rest-api"
 )
 
 type TodoList interface {
-	Create(userId int, list todo.TodoList) (int, error)
-	GetAll(userId int) ([]todo.TodoList, error)
-	GetById(userId, listId int) (todo.TodoList, error)
-	Update(userId, listId int, input todo.UpdateListInput) error
-	Delete(userId, listId int) error
+	Create(ctx context.Context, userId, workspaceId int, list todo.TodoList) (int, error)
+	GetAll(ctx context.Context, userId, workspaceId int) ([]todo.TodoList, error)
+	GetById(ctx context.Context, userId, workspaceId, listId int) (todo.TodoList, error)
+	Update(ctx context.Context, userId, workspaceId, listId int, input todo.UpdateListInput) error
+	Patch(ctx context.Context, userId, workspaceId, listId int, patch jsonpatch.Patch) (todo.TodoList, error)
+	Delete(ctx context.Context, userId, workspaceId, listId int) error
 }
 
 type Authorization interface {
-	CreateUser(user todo.User) (int, error)
-	GenerateToken(username, password string) (string, error)
-	ParseToken(token string) (int, error)
+	CreateUser(ctx context.Context, user todo.User) (int, error)
+	Authenticate(ctx context.Context, username, password string) (todo.User, error)
+	ChangePassword(ctx context.Context, username, password, newPassword, code string) error
+	GetUserById(ctx context.Context, userId int) (todo.User, error)
+	GenerateToken(ctx context.Context, userId int, scopes todo.Scopes) (string, error)
+	ParseToken(ctx context.Context, token string) (todo.Principal, error)
+	JWKS() JWKSet
+}
+
+type TwoFactor interface {
+	Enroll(ctx context.Context, userId int) (todo.TOTPEnrollment, error)
+	Confirm(ctx context.Context, userId int, code string) ([]string, error)
+	GenerateChallengeToken(userId int) (string, error)
+	VerifyChallenge(ctx context.Context, challengeToken, code string) (int, error)
+}
+
+type OIDC interface {
+	LoginURL() (string, string, error)
+	LinkURL(userId int) (string, string, error)
+	Callback(ctx context.Context, code, state, loginState string) (int, error)
+}
+
+type ApiKey interface {
+	Create(ctx context.Context, userId int, input todo.CreateApiKeyInput) (todo.ApiKey, string, error)
+	GetAll(ctx context.Context, userId int) ([]todo.ApiKey, error)
+	Delete(ctx context.Context, userId, keyId int) error
+	IsApiKey(token string) bool
+	ParseKey(ctx context.Context, key string) (todo.ApiKey, error)
+}
+
+type Admin interface {
+	GetUsers(ctx context.Context, search string, limit, offset int) ([]todo.UserAccount, error)
+	SetDisabled(ctx context.Context, userId int, disabled bool) error
+	RequirePasswordReset(ctx context.Context, userId int) error
+	GetUsage(ctx context.Context) ([]todo.UserUsage, error)
+	TransferList(ctx context.Context, listId, userId int) error
+}
+
+type Workspace interface {
+	Create(ctx context.Context, userId int, workspace todo.Workspace) (int, error)
+	GetAll(ctx context.Context, userId int) ([]todo.Workspace, error)
+	GetById(ctx context.Context, userId, workspaceId int) (todo.Workspace, error)
+	GetPersonal(ctx context.Context, userId int) (todo.Workspace, error)
+	GetMembers(ctx context.Context, userId, workspaceId int) ([]todo.WorkspaceMember, error)
+	AddMember(ctx context.Context, userId, workspaceId int, input todo.AddWorkspaceMemberInput) error
+	RemoveMember(ctx context.Context, userId, workspaceId, memberId int) error
+}
+
+type Idempotency interface {
+	Begin(ctx context.Context, userId int, key, fingerprint string) (*todo.IdempotencyRecord, error)
+	Complete(ctx context.Context, userId int, key string, statusCode int, contentType string, body []byte) error
+	Abort(ctx context.Context, userId int, key string) error
+}
+
+type Health interface {
+	Ready(ctx context.Context) todo.Readiness
+	Status(ctx context.Context) todo.ServiceStatus
+	BeginShutdown()
 }
 
 type TodoItem interface {
-	Create(userId, listId int, item todo.TodoItem) (int, error)
-	GetAll(userId, listId int) ([]todo.TodoItem, error)
-	GetById(userId, itemId int) (todo.TodoItem, error)
-	Update(userId, itemId int, input todo.UpdateItemInput) error
-	Delete(userId, itemId int) error
+	Create(ctx context.Context, userId, workspaceId, listId int, item todo.TodoItem) (int, error)
+	GetAll(ctx context.Context, userId, workspaceId, listId int) ([]todo.TodoItem, error)
+	GetAllByLists(ctx context.Context, userId, workspaceId int, listIds []int) (map[int][]todo.TodoItem, error)
+	GetById(ctx context.Context, userId, workspaceId, itemId int) (todo.TodoItem, error)
+	Update(ctx context.Context, userId, workspaceId, itemId int, input todo.UpdateItemInput) error
+	Patch(ctx context.Context, userId, workspaceId, itemId int, patch jsonpatch.Patch) (todo.TodoItem, error)
+	Delete(ctx context.Context, userId, workspaceId, itemId int) error
+}
+
+// WithPrimary makes list and item reads made with ctx see writes made just
+// before, which the replica they are otherwise served from may lag behind.
+func WithPrimary(ctx context.Context) context.Context {
+	return repository.WithPrimary(ctx)
 }
 
 type Service struct {
 	Authorization
+	TwoFactor
+	OIDC
+	ApiKey
+	Admin
+	Workspace
+	Idempotency
 	TodoList
 	TodoItem
+	Health
 }
 
-func NewService(repos *repository.Repository) *Service {
-	return &Service{
-		Authorization: NewAuthService(repos.Authorization),
+func NewService(repos *repository.Repository, keys *KeySet, provider *oidc.Provider, auth AuthConfig, build todo.BuildInfo) *Service {
+	services := &Service{
+		Authorization: NewAuthService(repos.Authorization, keys, auth),
+		TwoFactor:     NewTwoFactorService(repos.Authorization, keys),
+		ApiKey:        NewApiKeyService(repos.ApiKey, repos.Authorization),
+		Admin:         NewAdminService(repos.Admin, repos.Authorization),
+		Workspace:     NewWorkspaceService(repos.Workspace),
+		Idempotency:   NewIdempotencyService(repos.Idempotency),
 		TodoList:      NewTodoListService(repos.TodoList),
 		TodoItem:	   NewTodoItemService(repos.TodoItem, repos.TodoList),
+		Health:        NewHealthService(repos.Health, build),
 	}
+
+	if provider != nil {
+		services.OIDC = NewOIDCService(provider, repos.Identity, keys)
+	}
+
+	return services
 }
@@ -1,7 +1,14 @@
 package service
 
 import (
+	"context"
+	"fmt"
+
+	"akhmet.com/rest-api/pkg/jsonpatch"
+	"akhmet.com/rest-api/pkg/metrics"
 	"akhmet.com/rest-api/pkg/repository"
+	"akhmet.com/rest-api/pkg/tracing"
+	"akhmet.com/rest-api/pkg/validate"
 	"akhmet.com/rest-api"
 )
 
@@ -14,30 +21,91 @@ func NewTodoItemService(repo repository.TodoItem, listRepo repository.TodoList)
 	return &TodoItemService{repo: repo, listRepo: listRepo}
 }
 
-func (s *TodoItemService) Create(userId, listId int, item todo.TodoItem) (int, error) {
-	_, err := s.listRepo.GetById(userId, listId)
+func (s *TodoItemService) Create(ctx context.Context, userId, workspaceId, listId int, item todo.TodoItem) (int, error) {
+	ctx, span := tracing.Start(ctx, "TodoItemService.Create")
+	defer span.End()
+
+	if err := validate.Struct(&item); err != nil {
+		return 0, err
+	}
+
+	// The list may have been created just before, so the check must not
+	// lag behind on the replica.
+	_, err := s.listRepo.GetById(repository.WithPrimary(ctx), userId, workspaceId, listId)
 	if err != nil {
 		return 0, err
 	}
 
-	return s.repo.Create(listId, item)
+	id, err := s.repo.Create(ctx, listId, item)
+	if err != nil {
+		return 0, err
+	}
+
+	metrics.ItemCreated()
+	return id, nil
+}
+
+func (s *TodoItemService) GetAll(ctx context.Context, userId, workspaceId, listId int) ([]todo.TodoItem, error) {
+	ctx, span := tracing.Start(ctx, "TodoItemService.GetAll")
+	defer span.End()
+
+	return s.repo.GetAll(ctx, userId, workspaceId, listId)
 }
 
-func (s *TodoItemService) GetAll(userId, listId int) ([]todo.TodoItem, error) {
-	return s.repo.GetAll(userId, listId)
+// GetAllByLists loads the items of several lists in one query, keyed by
+// list id.
+func (s *TodoItemService) GetAllByLists(ctx context.Context, userId, workspaceId int, listIds []int) (map[int][]todo.TodoItem, error) {
+	ctx, span := tracing.Start(ctx, "TodoItemService.GetAllByLists")
+	defer span.End()
+
+	return s.repo.GetAllByLists(ctx, userId, workspaceId, listIds)
 }
 
-func (s *TodoItemService) GetById(userId, itemId int) (todo.TodoItem, error) {
-	return s.repo.GetById(userId, itemId)
+func (s *TodoItemService) GetById(ctx context.Context, userId, workspaceId, itemId int) (todo.TodoItem, error) {
+	ctx, span := tracing.Start(ctx, "TodoItemService.GetById")
+	defer span.End()
+
+	return s.repo.GetById(ctx, userId, workspaceId, itemId)
 }
 
-func (s *TodoItemService) Delete(userId, itemId int) error {
-	return s.repo.Delete(userId, itemId)
+func (s *TodoItemService) Delete(ctx context.Context, userId, workspaceId, itemId int) error {
+	ctx, span := tracing.Start(ctx, "TodoItemService.Delete")
+	defer span.End()
+
+	return s.repo.Delete(ctx, userId, workspaceId, itemId)
 }
 
-func (s *TodoItemService) Update(userId, itemId int, input todo.UpdateItemInput) error {
+func (s *TodoItemService) Update(ctx context.Context, userId, workspaceId, itemId int, input todo.UpdateItemInput) error {
+	ctx, span := tracing.Start(ctx, "TodoItemService.Update")
+	defer span.End()
+
 	if err := input.Validate(); err != nil {
 		return err
 	}
-	return s.repo.Update(userId, itemId, input)
+	return s.repo.Update(ctx, userId, workspaceId, itemId, input)
+}
+
+// Patch applies a merge patch or json patch to the item and stores the
+// result. The id member is read-only. The item stays locked from reading
+// to storing it, so concurrent patches both apply.
+func (s *TodoItemService) Patch(ctx context.Context, userId, workspaceId, itemId int, patch jsonpatch.Patch) (todo.TodoItem, error) {
+	ctx, span := tracing.Start(ctx, "TodoItemService.Patch")
+	defer span.End()
+
+	return s.repo.Patch(ctx, userId, workspaceId, itemId, func(item todo.TodoItem) (todo.TodoItem, error) {
+		var patched todo.TodoItem
+		if err := applyPatch(item, patch, &patched, "id", "title", "done"); err != nil {
+			return todo.TodoItem{}, err
+		}
+
+		if patched.Id != item.Id {
+			return todo.TodoItem{}, fmt.Errorf("%w: id is read-only", ErrInvalidPatchResult)
+		}
+
+		if err := validate.Struct(&patched); err != nil {
+			return todo.TodoItem{}, err
+		}
+
+		return patched, nil
+	})
 }
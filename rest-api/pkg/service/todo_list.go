@@ -1,7 +1,14 @@
 package service
 
 import (
+	"context"
+	"fmt"
+
+	"akhmet.com/rest-api/pkg/jsonpatch"
+	"akhmet.com/rest-api/pkg/metrics"
 	"akhmet.com/rest-api/pkg/repository"
+	"akhmet.com/rest-api/pkg/tracing"
+	"akhmet.com/rest-api/pkg/validate"
 	"akhmet.com/rest-api"
 )
 
@@ -13,25 +20,75 @@ func NewTodoListService(repo repository.TodoList) *TodoListService {
 	return &TodoListService{repo: repo}
 }
 
-func (s *TodoListService) Create(userId int, list todo.TodoList) (int, error) {
-	return s.repo.Create(userId, list)
+func (s *TodoListService) Create(ctx context.Context, userId, workspaceId int, list todo.TodoList) (int, error) {
+	ctx, span := tracing.Start(ctx, "TodoListService.Create")
+	defer span.End()
+
+	if err := validate.Struct(&list); err != nil {
+		return 0, err
+	}
+
+	id, err := s.repo.Create(ctx, userId, workspaceId, list)
+	if err != nil {
+		return 0, err
+	}
+
+	metrics.ListCreated()
+	return id, nil
 }
 
-func (s *TodoListService) GetAll(userId int) ([]todo.TodoList, error) {
-	return s.repo.GetAll(userId)
+func (s *TodoListService) GetAll(ctx context.Context, userId, workspaceId int) ([]todo.TodoList, error) {
+	ctx, span := tracing.Start(ctx, "TodoListService.GetAll")
+	defer span.End()
+
+	return s.repo.GetAll(ctx, userId, workspaceId)
 }
 
-func (s *TodoListService) GetById(userId, listId int) (todo.TodoList, error) {
-	return s.repo.GetById(userId, listId)
+func (s *TodoListService) GetById(ctx context.Context, userId, workspaceId, listId int) (todo.TodoList, error) {
+	ctx, span := tracing.Start(ctx, "TodoListService.GetById")
+	defer span.End()
+
+	return s.repo.GetById(ctx, userId, workspaceId, listId)
 }
 
-func (s *TodoListService) Delete(userId, listId int) error {
-	return s.repo.Delete(userId, listId)
+func (s *TodoListService) Delete(ctx context.Context, userId, workspaceId, listId int) error {
+	ctx, span := tracing.Start(ctx, "TodoListService.Delete")
+	defer span.End()
+
+	return s.repo.Delete(ctx, userId, workspaceId, listId)
 }
 
-func (s *TodoListService) Update(userId, listId int, input todo.UpdateListInput) error {
+func (s *TodoListService) Update(ctx context.Context, userId, workspaceId, listId int, input todo.UpdateListInput) error {
+	ctx, span := tracing.Start(ctx, "TodoListService.Update")
+	defer span.End()
+
 	if err := input.Validate(); err != nil {
 		return err
 	}
-	return s.repo.Update(userId, listId, input)
+	return s.repo.Update(ctx, userId, workspaceId, listId, input)
+}
+
+// Patch applies a merge patch or json patch to the list and stores the
+// result. The id and workspace_id members are read-only. The list stays
+// locked from reading to storing it, so concurrent patches both apply.
+func (s *TodoListService) Patch(ctx context.Context, userId, workspaceId, listId int, patch jsonpatch.Patch) (todo.TodoList, error) {
+	ctx, span := tracing.Start(ctx, "TodoListService.Patch")
+	defer span.End()
+
+	return s.repo.Patch(ctx, userId, workspaceId, listId, func(list todo.TodoList) (todo.TodoList, error) {
+		var patched todo.TodoList
+		if err := applyPatch(list, patch, &patched, "id", "workspace_id", "title"); err != nil {
+			return todo.TodoList{}, err
+		}
+
+		if patched.Id != list.Id || patched.WorkspaceId != list.WorkspaceId {
+			return todo.TodoList{}, fmt.Errorf("%w: id and workspace_id are read-only", ErrInvalidPatchResult)
+		}
+
+		if err := validate.Struct(&patched); err != nil {
+			return todo.TodoList{}, err
+		}
+
+		return patched, nil
+	})
 }
\ No newline at end of file
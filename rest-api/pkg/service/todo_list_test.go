@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/jsonpatch"
+	"akhmet.com/rest-api/pkg/repository"
+)
+
+// lockingListRepo stores one list and serializes Patch calls the way the
+// row lock does in Postgres.
+type lockingListRepo struct {
+	repository.TodoList
+	mu   sync.Mutex
+	list todo.TodoList
+}
+
+func (r *lockingListRepo) Patch(ctx context.Context, userId, workspaceId, listId int, apply func(todo.TodoList) (todo.TodoList, error)) (todo.TodoList, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	patched, err := apply(r.list)
+	if err != nil {
+		return todo.TodoList{}, err
+	}
+	r.list = patched
+	return patched, nil
+}
+
+func TestPatchListKeepsConcurrentUpdates(t *testing.T) {
+	repo := &lockingListRepo{list: todo.TodoList{Id: 1, WorkspaceId: 1, Title: "groceries"}}
+	s := NewTodoListService(repo)
+
+	var wg sync.WaitGroup
+	for _, body := range []string{`{"title":"shopping"}`, `{"description":"for the weekend"}`} {
+		patch, err := jsonpatch.DecodeMergePatch([]byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.Patch(context.Background(), 1, 1, 1, patch); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if repo.list.Title != "shopping" || repo.list.Description == nil || *repo.list.Description != "for the weekend" {
+		t.Fatalf("an update was lost: %+v", repo.list)
+	}
+}
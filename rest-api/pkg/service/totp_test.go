@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/repository"
+)
+
+// rfc6238Secret is the SHA1 seed of RFC 6238 appendix B, "12345678901234567890".
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+// The appendix lists 8 digit codes; the 6 digit codes are their last six
+// digits.
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "94287082"},
+	{1111111109, "07081804"},
+	{1111111111, "14050471"},
+	{1234567890, "89005924"},
+	{2000000000, "69279037"},
+	{20000000000, "65353130"},
+}
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		want := v.code[len(v.code)-totpDigits:]
+
+		got, err := totpCode(rfc6238Secret, v.unix/totpPeriod)
+		if err != nil {
+			t.Fatalf("time %d: %v", v.unix, err)
+		}
+		if got != want {
+			t.Errorf("time %d: got %s, want %s", v.unix, got, want)
+		}
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		code := v.code[len(v.code)-totpDigits:]
+		want := v.unix / totpPeriod
+
+		for _, offset := range []int64{-totpPeriod, 0, totpPeriod} {
+			step, ok := matchTOTP(rfc6238Secret, code, time.Unix(v.unix+offset, 0))
+			if !ok || step != want {
+				t.Errorf("time %d%+d: got step %d, %v, want %d", v.unix, offset, step, ok, want)
+			}
+		}
+
+		if _, ok := matchTOTP(rfc6238Secret, code, time.Unix(v.unix+3*totpPeriod, 0)); ok {
+			t.Errorf("time %d: code accepted three steps later", v.unix)
+		}
+	}
+
+	if _, ok := matchTOTP(rfc6238Secret, "12345", time.Unix(59, 0)); ok {
+		t.Error("short code accepted")
+	}
+}
+
+// fakeTOTPRepo keeps the last used step in memory like UseTOTPStep does in
+// the database.
+type fakeTOTPRepo struct {
+	repository.Authorization
+	user     todo.User
+	lastStep int64
+
+	failedAttempts int
+	lockedUntil    *time.Time
+}
+
+func (r *fakeTOTPRepo) GetUserById(ctx context.Context, userId int) (todo.User, error) {
+	return r.user, nil
+}
+
+func (r *fakeTOTPRepo) UseTOTPStep(ctx context.Context, userId int, step int64) error {
+	if step <= r.lastStep {
+		return sql.ErrNoRows
+	}
+	r.lastStep = step
+	return nil
+}
+
+func (r *fakeTOTPRepo) TakeTOTPAttempt(ctx context.Context, userId, maxAttempts int, lockUntil, now time.Time) error {
+	if r.lockedUntil != nil {
+		if r.lockedUntil.After(now) {
+			return sql.ErrNoRows
+		}
+		r.lockedUntil, r.failedAttempts = nil, 0
+	}
+
+	r.failedAttempts++
+	if r.failedAttempts >= maxAttempts {
+		r.lockedUntil = &lockUntil
+	}
+	return nil
+}
+
+func (r *fakeTOTPRepo) ResetTOTPAttempts(ctx context.Context, userId int) error {
+	r.failedAttempts, r.lockedUntil = 0, nil
+	return nil
+}
+
+func (r *fakeTOTPRepo) UseRecoveryCode(ctx context.Context, userId int, codeHash string) error {
+	return sql.ErrNoRows
+}
+
+func TestVerifyChallengeRejectsReplayedCode(t *testing.T) {
+	keys, err := NewEphemeralKeySet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &fakeTOTPRepo{user: todo.User{Id: 1, TOTPSecret: rfc6238Secret, TOTPEnabled: true}}
+	s := NewTwoFactorService(repo, keys)
+
+	challenge, err := s.GenerateChallengeToken(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := totpCode(rfc6238Secret, time.Now().Unix()/totpPeriod)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.VerifyChallenge(context.Background(), challenge, code); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if _, err := s.VerifyChallenge(context.Background(), challenge, code); err != ErrInvalidTwoFactorCode {
+		t.Fatalf("replay: got %v, want %v", err, ErrInvalidTwoFactorCode)
+	}
+
+	previous, err := totpCode(rfc6238Secret, time.Now().Unix()/totpPeriod-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.VerifyChallenge(context.Background(), challenge, previous); err != ErrInvalidTwoFactorCode {
+		t.Fatalf("earlier step: got %v, want %v", err, ErrInvalidTwoFactorCode)
+	}
+}
+
+func TestVerifyChallengeLocksAfterFailedAttempts(t *testing.T) {
+	keys, err := NewEphemeralKeySet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &fakeTOTPRepo{user: todo.User{Id: 1, TOTPSecret: rfc6238Secret, TOTPEnabled: true}}
+	s := NewTwoFactorService(repo, keys)
+
+	challenge, err := s.GenerateChallengeToken(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := totpCode(rfc6238Secret, time.Now().Unix()/totpPeriod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrong := "000000"
+	if wrong == code {
+		wrong = "111111"
+	}
+
+	for i := 0; i < maxTwoFactorAttempts; i++ {
+		if _, err := s.VerifyChallenge(context.Background(), challenge, wrong); err != ErrInvalidTwoFactorCode {
+			t.Fatalf("attempt %d: got %v, want %v", i+1, err, ErrInvalidTwoFactorCode)
+		}
+	}
+
+	if _, err := s.VerifyChallenge(context.Background(), challenge, code); err != ErrTwoFactorLocked {
+		t.Fatalf("valid code while locked: got %v, want %v", err, ErrTwoFactorLocked)
+	}
+
+	other, err := s.GenerateChallengeToken(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.VerifyChallenge(context.Background(), other, code); err != ErrTwoFactorLocked {
+		t.Fatalf("new challenge while locked: got %v, want %v", err, ErrTwoFactorLocked)
+	}
+
+	expired := time.Now().Add(-time.Second)
+	repo.lockedUntil = &expired
+	if _, err := s.VerifyChallenge(context.Background(), challenge, code); err != nil {
+		t.Fatalf("after lockout: %v", err)
+	}
+	if repo.failedAttempts != 0 {
+		t.Errorf("failed attempts = %d after success, want 0", repo.failedAttempts)
+	}
+}
+
+func TestFailedAttemptsResetOnSuccess(t *testing.T) {
+	keys, err := NewEphemeralKeySet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &fakeTOTPRepo{user: todo.User{Id: 1, TOTPSecret: rfc6238Secret, TOTPEnabled: true}}
+	s := NewTwoFactorService(repo, keys)
+
+	challenge, err := s.GenerateChallengeToken(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for round := 0; round < 3; round++ {
+		for i := 0; i < maxTwoFactorAttempts-1; i++ {
+			s.VerifyChallenge(context.Background(), challenge, "not-a-code")
+		}
+
+		repo.lastStep = 0
+		code, err := totpCode(rfc6238Secret, time.Now().Unix()/totpPeriod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.VerifyChallenge(context.Background(), challenge, code); err != nil {
+			t.Fatalf("round %d: %v", round+1, err)
+		}
+	}
+}
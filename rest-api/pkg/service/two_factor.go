@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/metrics"
+	"akhmet.com/rest-api/pkg/repository"
+	"akhmet.com/rest-api/pkg/tracing"
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	challengeTokenType = "2fa_challenge"
+	challengeTokenTTL  = 5 * time.Minute
+	recoveryCodesCount = 10
+
+	// maxTwoFactorAttempts invalid codes in a row lock two-factor
+	// verification of the account for twoFactorLockout.
+	maxTwoFactorAttempts = 5
+	twoFactorLockout     = 15 * time.Minute
+)
+
+var (
+	ErrInvalidTwoFactorCode  = errors.New("invalid two-factor code")
+	ErrTwoFactorEnabled      = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnrolled  = errors.New("two-factor enrollment has not been started")
+	ErrInvalidChallengeToken = errors.New("invalid challenge token")
+	ErrTwoFactorLocked       = errors.New("too many invalid two-factor codes, try again later")
+)
+
+type TwoFactorService struct {
+	repo repository.Authorization
+	keys *KeySet
+}
+
+func NewTwoFactorService(repo repository.Authorization, keys *KeySet) *TwoFactorService {
+	return &TwoFactorService{repo: repo, keys: keys}
+}
+
+func (s *TwoFactorService) Enroll(ctx context.Context, userId int) (todo.TOTPEnrollment, error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.Enroll")
+	defer span.End()
+
+	user, err := s.repo.GetUserById(ctx, userId)
+	if err != nil {
+		return todo.TOTPEnrollment{}, err
+	}
+
+	if user.TOTPEnabled {
+		return todo.TOTPEnrollment{}, ErrTwoFactorEnabled
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return todo.TOTPEnrollment{}, err
+	}
+
+	if err := s.repo.SetTOTPSecret(ctx, userId, secret); err != nil {
+		return todo.TOTPEnrollment{}, err
+	}
+
+	return todo.TOTPEnrollment{
+		Secret: secret,
+		URI:    totpURI(user.Username, secret),
+	}, nil
+}
+
+func (s *TwoFactorService) Confirm(ctx context.Context, userId int, code string) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.Confirm")
+	defer span.End()
+
+	user, err := s.repo.GetUserById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	ok, err := useTOTP(ctx, s.repo, user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+	for i := 0; i < recoveryCodesCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	if err := s.repo.EnableTOTP(ctx, userId, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (s *TwoFactorService) GenerateChallengeToken(userId int) (string, error) {
+	return s.keys.sign(&tokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(challengeTokenTTL).Unix(),
+			IssuedAt:  time.Now().Unix(),
+		},
+		UserId:    userId,
+		TokenType: challengeTokenType,
+	})
+}
+
+// VerifyChallenge accepts either a current TOTP code that was not used
+// before or an unused recovery code and returns the id of the user the
+// challenge was issued for. Failed attempts count towards the lockout of
+// verifyCode.
+func (s *TwoFactorService) VerifyChallenge(ctx context.Context, challengeToken, code string) (userId int, err error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.VerifyChallenge")
+	defer span.End()
+	defer func() { metrics.AuthAttempt(metrics.AuthTwoFactor, err) }()
+
+	claims, err := s.keys.parseTokenClaims(challengeToken)
+	if err != nil {
+		return 0, err
+	}
+
+	if claims.TokenType != challengeTokenType {
+		return 0, ErrInvalidChallengeToken
+	}
+
+	user, err := s.repo.GetUserById(ctx, claims.UserId)
+	if err != nil {
+		return 0, err
+	}
+
+	if !user.TOTPEnabled {
+		return 0, ErrInvalidChallengeToken
+	}
+
+	if err := verifyCode(ctx, s.repo, user, code); err != nil {
+		return 0, err
+	}
+
+	return user.Id, nil
+}
+
+// verifyCode accepts a TOTP code that was not used before or an unused
+// recovery code. Every attempt is counted before the code is checked, and
+// maxTwoFactorAttempts failures in a row lock verification for
+// twoFactorLockout, so a six digit code cannot be guessed within the
+// lifetime of a challenge.
+func verifyCode(ctx context.Context, repo repository.Authorization, user todo.User, code string) error {
+	now := time.Now()
+	if err := repo.TakeTOTPAttempt(ctx, user.Id, maxTwoFactorAttempts, now.Add(twoFactorLockout), now); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTwoFactorLocked
+		}
+		return err
+	}
+
+	ok, err := useTOTP(ctx, repo, user, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if err := repo.UseRecoveryCode(ctx, user.Id, hashRecoveryCode(code)); err != nil {
+			return ErrInvalidTwoFactorCode
+		}
+	}
+
+	return repo.ResetTOTPAttempts(ctx, user.Id)
+}
+
+func generateRecoveryCode() (string, error) {
+	buf := make([]byte, 5)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	code := hex.EncodeToString(buf)
+	return code[:5] + "-" + code[5:], nil
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/repository"
+	"akhmet.com/rest-api/pkg/tracing"
+)
+
+var (
+	ErrWorkspaceOwnerRequired = errors.New("only workspace owners can manage members")
+	ErrInvalidWorkspaceRole   = errors.New("role must be either owner or member")
+	ErrPersonalWorkspace      = errors.New("personal workspaces cannot be shared")
+	ErrLastWorkspaceOwner     = errors.New("the last owner cannot leave a workspace")
+)
+
+type WorkspaceService struct {
+	repo repository.Workspace
+}
+
+func NewWorkspaceService(repo repository.Workspace) *WorkspaceService {
+	return &WorkspaceService{repo: repo}
+}
+
+func (s *WorkspaceService) Create(ctx context.Context, userId int, workspace todo.Workspace) (int, error) {
+	ctx, span := tracing.Start(ctx, "WorkspaceService.Create")
+	defer span.End()
+
+	return s.repo.Create(ctx, userId, workspace)
+}
+
+func (s *WorkspaceService) GetAll(ctx context.Context, userId int) ([]todo.Workspace, error) {
+	ctx, span := tracing.Start(ctx, "WorkspaceService.GetAll")
+	defer span.End()
+
+	return s.repo.GetAll(ctx, userId)
+}
+
+func (s *WorkspaceService) GetById(ctx context.Context, userId, workspaceId int) (todo.Workspace, error) {
+	ctx, span := tracing.Start(ctx, "WorkspaceService.GetById")
+	defer span.End()
+
+	return s.repo.GetById(ctx, userId, workspaceId)
+}
+
+func (s *WorkspaceService) GetPersonal(ctx context.Context, userId int) (todo.Workspace, error) {
+	ctx, span := tracing.Start(ctx, "WorkspaceService.GetPersonal")
+	defer span.End()
+
+	return s.repo.GetPersonal(ctx, userId)
+}
+
+func (s *WorkspaceService) GetMembers(ctx context.Context, userId, workspaceId int) ([]todo.WorkspaceMember, error) {
+	ctx, span := tracing.Start(ctx, "WorkspaceService.GetMembers")
+	defer span.End()
+
+	if _, err := s.repo.GetById(ctx, userId, workspaceId); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetMembers(ctx, workspaceId)
+}
+
+func (s *WorkspaceService) AddMember(ctx context.Context, userId, workspaceId int, input todo.AddWorkspaceMemberInput) error {
+	ctx, span := tracing.Start(ctx, "WorkspaceService.AddMember")
+	defer span.End()
+
+	if input.Role == "" {
+		input.Role = todo.WorkspaceRoleMember
+	}
+
+	if input.Role != todo.WorkspaceRoleOwner && input.Role != todo.WorkspaceRoleMember {
+		return ErrInvalidWorkspaceRole
+	}
+
+	workspace, err := s.ownedWorkspace(ctx, userId, workspaceId)
+	if err != nil {
+		return err
+	}
+
+	if workspace.Personal {
+		return ErrPersonalWorkspace
+	}
+
+	return s.repo.AddMember(ctx, workspaceId, input.Username, input.Role)
+}
+
+func (s *WorkspaceService) RemoveMember(ctx context.Context, userId, workspaceId, memberId int) error {
+	ctx, span := tracing.Start(ctx, "WorkspaceService.RemoveMember")
+	defer span.End()
+
+	// Members may leave a workspace on their own; removing anyone else
+	// takes an owner.
+	var workspace todo.Workspace
+	var err error
+	if userId == memberId {
+		workspace, err = s.repo.GetById(ctx, userId, workspaceId)
+	} else {
+		workspace, err = s.ownedWorkspace(ctx, userId, workspaceId)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Lists without a selected workspace go to the personal one, so its
+	// owner must stay in it.
+	if workspace.Personal {
+		return ErrPersonalWorkspace
+	}
+
+	members, err := s.repo.GetMembers(ctx, workspaceId)
+	if err != nil {
+		return err
+	}
+
+	if isLastOwner(members, memberId) {
+		return ErrLastWorkspaceOwner
+	}
+
+	return s.repo.RemoveMember(ctx, workspaceId, memberId)
+}
+
+func isLastOwner(members []todo.WorkspaceMember, userId int) bool {
+	owners, isOwner := 0, false
+	for _, member := range members {
+		if member.Role != todo.WorkspaceRoleOwner {
+			continue
+		}
+		owners++
+		if member.UserId == userId {
+			isOwner = true
+		}
+	}
+
+	return isOwner && owners == 1
+}
+
+func (s *WorkspaceService) ownedWorkspace(ctx context.Context, userId, workspaceId int) (todo.Workspace, error) {
+	workspace, err := s.repo.GetById(ctx, userId, workspaceId)
+	if err != nil {
+		return workspace, err
+	}
+
+	if workspace.Role != todo.WorkspaceRoleOwner {
+		return workspace, ErrWorkspaceOwnerRequired
+	}
+
+	return workspace, nil
+}
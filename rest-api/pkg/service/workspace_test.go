@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"akhmet.com/rest-api"
+	"akhmet.com/rest-api/pkg/repository"
+)
+
+// fakeWorkspaceRepo holds a single workspace and its members.
+type fakeWorkspaceRepo struct {
+	repository.Workspace
+	workspace todo.Workspace
+	members   []todo.WorkspaceMember
+}
+
+func (r *fakeWorkspaceRepo) GetById(ctx context.Context, userId, workspaceId int) (todo.Workspace, error) {
+	for _, member := range r.members {
+		if member.UserId == userId && workspaceId == r.workspace.Id {
+			workspace := r.workspace
+			workspace.Role = member.Role
+			return workspace, nil
+		}
+	}
+	return todo.Workspace{}, sql.ErrNoRows
+}
+
+func (r *fakeWorkspaceRepo) GetMembers(ctx context.Context, workspaceId int) ([]todo.WorkspaceMember, error) {
+	return r.members, nil
+}
+
+func (r *fakeWorkspaceRepo) RemoveMember(ctx context.Context, workspaceId, userId int) error {
+	for i, member := range r.members {
+		if member.UserId == userId {
+			r.members = append(r.members[:i], r.members[i+1:]...)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func TestRemoveMember(t *testing.T) {
+	owner := todo.WorkspaceMember{UserId: 1, Role: todo.WorkspaceRoleOwner}
+	coOwner := todo.WorkspaceMember{UserId: 2, Role: todo.WorkspaceRoleOwner}
+	member := todo.WorkspaceMember{UserId: 3, Role: todo.WorkspaceRoleMember}
+
+	for _, tt := range []struct {
+		name             string
+		personal         bool
+		members          []todo.WorkspaceMember
+		userId, memberId int
+		want             error
+	}{
+		{"leave personal workspace", true, []todo.WorkspaceMember{owner}, 1, 1, ErrPersonalWorkspace},
+		{"last owner leaves", false, []todo.WorkspaceMember{owner, member}, 1, 1, ErrLastWorkspaceOwner},
+		{"owner leaves with a co-owner", false, []todo.WorkspaceMember{owner, coOwner}, 1, 1, nil},
+		{"member leaves", false, []todo.WorkspaceMember{owner, member}, 3, 3, nil},
+		{"member removes owner", false, []todo.WorkspaceMember{owner, member}, 3, 1, ErrWorkspaceOwnerRequired},
+		{"owner removes member", false, []todo.WorkspaceMember{owner, member}, 1, 3, nil},
+		{"co-owner removes last other owner", false, []todo.WorkspaceMember{owner, coOwner}, 2, 1, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeWorkspaceRepo{
+				workspace: todo.Workspace{Id: 10, Personal: tt.personal},
+				members:   append([]todo.WorkspaceMember(nil), tt.members...),
+			}
+			s := NewWorkspaceService(repo)
+
+			if err := s.RemoveMember(context.Background(), tt.userId, 10, tt.memberId); err != tt.want {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+			if removed := len(repo.members) < len(tt.members); removed != (tt.want == nil) {
+				t.Fatalf("member removed: %v", removed)
+			}
+		})
+	}
+}
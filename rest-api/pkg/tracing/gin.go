@@ -0,0 +1,47 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// unmatchedRoute names spans of requests that matched no route, so probes
+// for arbitrary paths do not create a span name each.
+const unmatchedRoute = "unmatched"
+
+// Middleware starts a server span for every request, continuing the trace
+// of the caller when the request carries a traceparent header. The span
+// is put into the request context for the handlers and services.
+func Middleware(c *gin.Context) {
+	route := c.FullPath()
+	if route == "" {
+		route = unmatchedRoute
+	}
+
+	ctx := Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+	ctx, span := Start(ctx, c.Request.Method+" "+route,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPMethodKey.String(c.Request.Method),
+			semconv.HTTPRouteKey.String(route),
+			semconv.HTTPTargetKey.String(c.Request.URL.Path),
+			semconv.HTTPUserAgentKey.String(c.Request.UserAgent()),
+			semconv.HTTPClientIPKey.String(c.ClientIP()),
+		),
+	)
+	defer span.End()
+
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+
+	status := c.Writer.Status()
+	span.SetAttributes(semconv.HTTPStatusCodeKey.Int(status))
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+}
@@ -0,0 +1,277 @@
+// Package validate checks and normalizes structs according to their
+// `validate` tags:
+//
+//	Title string `json:"title" validate:"trim,required,max=255,charset=text"`
+//
+// Rules run in tag order and stop at the first failure of a field, so
+// every failing field is reported once.
+//
+//	trim        strip surrounding white space (modifies the value)
+//	required    must be present and non-empty
+//	notempty    may be absent (nil) but must not be empty when present
+//	min=N       at least N characters or elements
+//	max=N       at most N characters or elements
+//	charset=C   only characters of a named set, see Charsets
+//	oneof=a b   one of the space separated values
+//
+// Nil pointers and empty values are only checked by required and
+// notempty, so optional fields pass the other rules when left out. Nested
+// structs are validated recursively and report dotted field names.
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	CodeRequired          = "required"
+	CodeTooShort          = "too_short"
+	CodeTooLong           = "too_long"
+	CodeInvalidCharacters = "invalid_characters"
+	CodeInvalidChoice     = "invalid_choice"
+	CodeInvalid           = "invalid"
+)
+
+// Charsets are the character sets usable with the charset rule.
+var Charsets = map[string]func(r rune) bool{
+	// text allows any printable character, including spaces.
+	"text": func(r rune) bool {
+		return unicode.IsPrint(r)
+	},
+	// username allows ASCII letters, digits, dot, dash and underscore.
+	"username": func(r rune) bool {
+		return r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '-' || r == '_')
+	},
+	// digits allows ASCII digits only.
+	"digits": func(r rune) bool {
+		return r >= '0' && r <= '9'
+	},
+}
+
+// FieldError describes why a single field is invalid.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors lists every invalid field of a struct.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fieldError := range e {
+		if fieldError.Field == "" {
+			messages = append(messages, fieldError.Message)
+			continue
+		}
+		messages = append(messages, fieldError.Field+": "+fieldError.Message)
+	}
+
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// Checker is implemented by types with rules that span several fields.
+// Its errors are reported after the tag rules.
+type Checker interface {
+	Check() Errors
+}
+
+// Struct validates v, which must be a pointer to a struct so that trim can
+// update it. It returns nil or an Errors value.
+func Struct(v interface{}) error {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		panic("validate: Struct needs a pointer to a struct, got " + value.Type().String())
+	}
+
+	var errs Errors
+	validateStruct(value.Elem(), "", &errs)
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+func validateStruct(value reflect.Value, prefix string, errs *Errors) {
+	t := value.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := prefix + fieldName(field)
+		fieldValue := value.Field(i)
+
+		if field.Anonymous && fieldValue.Kind() == reflect.Struct {
+			validateStruct(fieldValue, prefix, errs)
+			continue
+		}
+
+		if tag := field.Tag.Get("validate"); tag != "" && tag != "-" {
+			if fieldError := validateField(fieldValue, tag); fieldError != nil {
+				fieldError.Field = name
+				*errs = append(*errs, *fieldError)
+				continue
+			}
+		}
+
+		nested := fieldValue
+		if nested.Kind() == reflect.Ptr && !nested.IsNil() {
+			nested = nested.Elem()
+		}
+		if nested.Kind() == reflect.Struct && nested.Type().PkgPath() != "time" {
+			validateStruct(nested, name+".", errs)
+		}
+	}
+
+	if value.CanAddr() {
+		if checker, ok := value.Addr().Interface().(Checker); ok {
+			for _, fieldError := range checker.Check() {
+				if prefix != "" {
+					fieldError.Field = strings.TrimSuffix(prefix+fieldError.Field, ".")
+				}
+				*errs = append(*errs, fieldError)
+			}
+		}
+	}
+}
+
+func validateField(value reflect.Value, tag string) *FieldError {
+	for _, rule := range strings.Split(tag, ",") {
+		name, param := rule, ""
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+
+		if value.Kind() == reflect.Ptr && value.IsNil() {
+			if name == "required" {
+				return &FieldError{Code: CodeRequired, Message: "is required"}
+			}
+			continue
+		}
+
+		target := reflect.Indirect(value)
+
+		if name == "trim" {
+			if target.Kind() == reflect.String && target.CanSet() {
+				target.SetString(strings.TrimSpace(target.String()))
+			}
+			continue
+		}
+
+		if isEmpty(target) {
+			switch name {
+			case "required":
+				return &FieldError{Code: CodeRequired, Message: "is required"}
+			case "notempty":
+				return &FieldError{Code: CodeRequired, Message: "must not be empty"}
+			}
+			continue
+		}
+
+		switch name {
+		case "required", "notempty":
+		case "min":
+			n := mustInt(rule, param)
+			if length(target) < n {
+				return &FieldError{Code: CodeTooShort, Message: fmt.Sprintf("must be at least %d %s", n, unit(target))}
+			}
+		case "max":
+			n := mustInt(rule, param)
+			if length(target) > n {
+				return &FieldError{Code: CodeTooLong, Message: fmt.Sprintf("must be at most %d %s", n, unit(target))}
+			}
+		case "charset":
+			allowed, ok := Charsets[param]
+			if !ok {
+				panic("validate: unknown charset " + param)
+			}
+			if strings.IndexFunc(target.String(), func(r rune) bool { return !allowed(r) }) >= 0 {
+				return &FieldError{Code: CodeInvalidCharacters, Message: "contains characters outside of the " + param + " set"}
+			}
+		case "oneof":
+			choices := strings.Fields(param)
+			if !contains(choices, fmt.Sprint(target.Interface())) {
+				return &FieldError{Code: CodeInvalidChoice, Message: "must be one of: " + strings.Join(choices, ", ")}
+			}
+		default:
+			panic("validate: unknown rule " + rule)
+		}
+	}
+
+	return nil
+}
+
+func fieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return field.Name
+	}
+
+	return name
+}
+
+func isEmpty(v reflect.Value) bool {
+	if hasLength(v) {
+		return v.Len() == 0
+	}
+
+	return v.IsZero()
+}
+
+func hasLength(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return true
+	default:
+		return false
+	}
+}
+
+func length(v reflect.Value) int {
+	if v.Kind() == reflect.String {
+		return utf8.RuneCountInString(v.String())
+	}
+	if hasLength(v) {
+		return v.Len()
+	}
+
+	return 0
+}
+
+func unit(v reflect.Value) string {
+	if v.Kind() == reflect.String {
+		return "characters"
+	}
+
+	return "elements"
+}
+
+func mustInt(rule, param string) int {
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		panic("validate: invalid rule " + rule)
+	}
+
+	return n
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,190 @@
+package validate
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	type input struct {
+		Value string `json:"value"`
+	}
+
+	tests := []struct {
+		tag   string
+		value string
+		code  string
+	}{
+		{"required", "", CodeRequired},
+		{"required", "x", ""},
+		{"trim,required", "  ", CodeRequired},
+		{"required,trim", "  ", ""},
+		{"notempty", "", CodeRequired},
+		{"min=3", "ab", CodeTooShort},
+		{"min=3", "abc", ""},
+		{"min=3", "", ""},
+		{"max=3", "abcd", CodeTooLong},
+		{"max=3", "äöü", ""},
+		{"charset=text", "line\nbreak", CodeInvalidCharacters},
+		{"charset=text", "any text, even ✓", ""},
+		{"charset=username", "jane.doe-1_", ""},
+		{"charset=username", "jane doe", CodeInvalidCharacters},
+		{"charset=username", "jänе", CodeInvalidCharacters},
+		{"charset=digits", "012", ""},
+		{"charset=digits", "12a", CodeInvalidCharacters},
+		{"oneof=a b", "b", ""},
+		{"oneof=a b", "c", CodeInvalidChoice},
+		{"min=1,max=2", "abc", CodeTooLong},
+	}
+
+	for _, test := range tests {
+		value := reflect.ValueOf(&input{Value: test.value}).Elem().Field(0)
+
+		fieldError := validateField(value, test.tag)
+		switch {
+		case test.code == "" && fieldError != nil:
+			t.Errorf("%s on %q: %+v, want no error", test.tag, test.value, *fieldError)
+		case test.code != "" && fieldError == nil:
+			t.Errorf("%s on %q: no error, want %s", test.tag, test.value, test.code)
+		case test.code != "" && fieldError.Code != test.code:
+			t.Errorf("%s on %q: code %s, want %s", test.tag, test.value, fieldError.Code, test.code)
+		}
+	}
+}
+
+func TestTrimUpdatesValue(t *testing.T) {
+	input := struct {
+		Title   string  `validate:"trim"`
+		Comment *string `validate:"trim"`
+	}{Title: "  title ", Comment: new(string)}
+	*input.Comment = "\tcomment\n"
+
+	if err := Struct(&input); err != nil {
+		t.Fatal(err)
+	}
+	if input.Title != "title" || *input.Comment != "comment" {
+		t.Errorf("trimmed to %q and %q", input.Title, *input.Comment)
+	}
+}
+
+func TestPointers(t *testing.T) {
+	type input struct {
+		Optional *string `json:"optional" validate:"notempty,max=3"`
+		Required *string `json:"required" validate:"required"`
+	}
+
+	empty, long := "", "long"
+
+	tests := []struct {
+		name  string
+		input input
+		want  Errors
+	}{
+		{"absent", input{Required: &long}, nil},
+		{"required absent", input{}, Errors{{Field: "required", Code: CodeRequired, Message: "is required"}}},
+		{"present but empty", input{Optional: &empty, Required: &long}, Errors{{Field: "optional", Code: CodeRequired, Message: "must not be empty"}}},
+		{"present and too long", input{Optional: &long, Required: &long}, Errors{{Field: "optional", Code: CodeTooLong, Message: "must be at most 3 characters"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var errs Errors
+			if err := Struct(&test.input); err != nil && !errors.As(err, &errs) {
+				t.Fatalf("err = %v, want Errors", err)
+			}
+			if !reflect.DeepEqual(errs, test.want) {
+				t.Errorf("errors = %+v, want %+v", errs, test.want)
+			}
+		})
+	}
+}
+
+type Address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type person struct {
+	Address
+	Name  string   `json:"name" validate:"required"`
+	Nick  string   `validate:"max=2"`
+	Tags  []string `json:"tags,omitempty" validate:"min=1,max=2"`
+	Home  Address  `json:"home"`
+	Work  *Address `json:"work"`
+	Other string   `json:"-" validate:"required"`
+}
+
+func (p *person) Check() Errors {
+	if p.Name == p.Nick {
+		return Errors{{Field: "nick", Code: CodeInvalid, Message: "must differ from name"}}
+	}
+	return nil
+}
+
+func TestFieldNames(t *testing.T) {
+	p := person{Nick: "", Tags: []string{"a", "b", "c"}, Work: &Address{}}
+
+	err := Struct(&p)
+
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("err = %v, want Errors", err)
+	}
+
+	var fields []string
+	for _, fieldError := range errs {
+		fields = append(fields, fieldError.Field)
+	}
+
+	want := []string{"city", "name", "tags", "home.city", "work.city", "Other", "nick"}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("fields = %v, want %v", fields, want)
+	}
+
+	body, err := json.Marshal(errs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `{"field":"city","code":"required","message":"is required"}` {
+		t.Errorf("json = %s", body)
+	}
+}
+
+func TestNestedCheckerPrefix(t *testing.T) {
+	input := struct {
+		Owner person `json:"owner"`
+	}{Owner: person{Address: Address{City: "x"}, Name: "a", Nick: "a", Home: Address{City: "y"}, Other: "x"}}
+
+	err := Struct(&input)
+
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("err = %v, want Errors", err)
+	}
+	if len(errs) != 1 || errs[0].Field != "owner.nick" {
+		t.Errorf("errors = %+v, want owner.nick", errs)
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	errs := Errors{
+		{Field: "title", Code: CodeRequired, Message: "is required"},
+		{Code: CodeRequired, Message: "update structure has no values"},
+	}
+
+	want := "validation failed: title: is required; update structure has no values"
+	if errs.Error() != want {
+		t.Errorf("Error() = %q, want %q", errs.Error(), want)
+	}
+}
+
+func TestStructNeedsPointer(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Struct accepted a value")
+		}
+	}()
+
+	Struct(struct{}{})
+}
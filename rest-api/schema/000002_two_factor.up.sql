@@ -0,0 +1,11 @@
+ALTER TABLE users
+    ADD COLUMN totp_secret  varchar(255) not null default '',
+    ADD COLUMN totp_enabled boolean      not null default false;
+
+CREATE TABLE recovery_codes
+(
+    id        serial                                      not null unique,
+    user_id   int references users (id) on delete cascade not null,
+    code_hash varchar(255)                                not null,
+    used      boolean                                     not null default false
+);
@@ -0,0 +1,8 @@
+CREATE TABLE user_identities
+(
+    id      serial                                      not null unique,
+    user_id int references users (id) on delete cascade not null,
+    issuer  varchar(255)                                not null,
+    subject varchar(255)                                not null,
+    unique (issuer, subject)
+);
@@ -0,0 +1,12 @@
+CREATE TABLE api_keys
+(
+    id           serial                                      not null unique,
+    user_id      int references users (id) on delete cascade not null,
+    name         varchar(255)                                not null,
+    prefix       varchar(32)                                 not null,
+    key_hash     varchar(255)                                not null unique,
+    scope        varchar(32)                                 not null,
+    expires_at   timestamptz,
+    last_used_at timestamptz,
+    created_at   timestamptz                                 not null default now()
+);
@@ -0,0 +1,7 @@
+ALTER TABLE api_keys
+    ADD COLUMN scope varchar(32) not null default 'read';
+
+UPDATE api_keys SET scope = 'write' WHERE scopes LIKE '%:write%';
+
+ALTER TABLE api_keys
+    DROP COLUMN scopes;
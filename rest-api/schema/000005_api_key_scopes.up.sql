@@ -0,0 +1,9 @@
+ALTER TABLE api_keys
+    ADD COLUMN scopes varchar(512) not null default '';
+
+UPDATE api_keys SET scopes = 'lists:read items:read' WHERE scope = 'read';
+
+UPDATE api_keys SET scopes = 'lists:read lists:write items:read items:write' WHERE scope = 'write';
+
+ALTER TABLE api_keys
+    DROP COLUMN scope;
@@ -0,0 +1,6 @@
+-- Grant the first administrator by hand:
+-- UPDATE users SET role = 'admin' WHERE username = '...';
+ALTER TABLE users
+    ADD COLUMN role                    varchar(32) not null default 'user',
+    ADD COLUMN disabled                boolean     not null default false,
+    ADD COLUMN password_reset_required boolean     not null default false;
@@ -0,0 +1,6 @@
+ALTER TABLE todo_lists
+    DROP COLUMN workspace_id;
+
+DROP TABLE workspace_members;
+
+DROP TABLE workspaces;
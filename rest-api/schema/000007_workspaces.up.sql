@@ -0,0 +1,34 @@
+CREATE TABLE workspaces
+(
+    id               serial                                      not null unique,
+    name             varchar(255)                                not null,
+    personal_user_id int references users (id) on delete cascade unique
+);
+
+CREATE TABLE workspace_members
+(
+    id           serial                                           not null unique,
+    workspace_id int references workspaces (id) on delete cascade not null,
+    user_id      int references users (id) on delete cascade      not null,
+    role         varchar(32)                                      not null default 'member',
+    unique (workspace_id, user_id)
+);
+
+INSERT INTO workspaces (name, personal_user_id)
+SELECT 'Personal', id FROM users;
+
+INSERT INTO workspace_members (workspace_id, user_id, role)
+SELECT id, personal_user_id, 'owner' FROM workspaces;
+
+ALTER TABLE todo_lists
+    ADD COLUMN workspace_id int references workspaces (id) on delete cascade;
+
+UPDATE todo_lists tl SET workspace_id = w.id
+FROM users_lists ul, workspaces w
+WHERE ul.list_id = tl.id AND w.personal_user_id = ul.user_id;
+
+-- Lists without an owner were unreachable before and cannot be placed.
+DELETE FROM todo_lists WHERE workspace_id IS NULL;
+
+ALTER TABLE todo_lists
+    ALTER COLUMN workspace_id SET NOT NULL;
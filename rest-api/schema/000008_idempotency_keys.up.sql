@@ -0,0 +1,11 @@
+CREATE TABLE idempotency_keys
+(
+    user_id      int references users (id) on delete cascade not null,
+    key          varchar(255)                                not null,
+    fingerprint  varchar(64)                                 not null,
+    status_code  int                                         not null default 0,
+    content_type varchar(255)                                not null default '',
+    body         bytea,
+    created_at   timestamptz                                 not null default now(),
+    primary key (user_id, key)
+);
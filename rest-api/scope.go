@@ -0,0 +1,96 @@
+package todo
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+const (
+	ScopeListsRead  = "lists:read"
+	ScopeListsWrite = "lists:write"
+	ScopeItemsRead  = "items:read"
+	ScopeItemsWrite = "items:write"
+	ScopeAccount    = "account"
+)
+
+// AllScopes are granted to tokens issued by an interactive sign-in.
+var AllScopes = Scopes{ScopeListsRead, ScopeListsWrite, ScopeItemsRead, ScopeItemsWrite, ScopeAccount}
+
+// ApiKeyScopes are the scopes an api key may hold. Account management is
+// left out so that a leaked key cannot mint new keys or change 2FA.
+var ApiKeyScopes = Scopes{ScopeListsRead, ScopeListsWrite, ScopeItemsRead, ScopeItemsWrite}
+
+// Scopes is stored and transmitted as a space separated string, the same
+// form the OAuth2 "scope" parameter uses.
+type Scopes []string
+
+func ParseScopes(value string) Scopes {
+	return Scopes(strings.Fields(value))
+}
+
+func (s Scopes) String() string {
+	return strings.Join(s, " ")
+}
+
+func (s Scopes) Has(scope string) bool {
+	for _, v := range s {
+		if v == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s Scopes) Missing(required ...string) []string {
+	var missing []string
+	for _, scope := range required {
+		if !s.Has(scope) {
+			missing = append(missing, scope)
+		}
+	}
+
+	return missing
+}
+
+// ValidateSubset checks that every scope is known and contained in allowed.
+func (s Scopes) ValidateSubset(allowed Scopes) error {
+	for _, scope := range s {
+		if !AllScopes.Has(scope) {
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+
+		if !allowed.Has(scope) {
+			return fmt.Errorf("scope %q is not allowed", scope)
+		}
+	}
+
+	return nil
+}
+
+func (s Scopes) Value() (driver.Value, error) {
+	return s.String(), nil
+}
+
+func (s *Scopes) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case string:
+		*s = ParseScopes(v)
+	case []byte:
+		*s = ParseScopes(string(v))
+	case nil:
+		*s = nil
+	default:
+		return fmt.Errorf("cannot scan %T into Scopes", src)
+	}
+
+	return nil
+}
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserId int
+	Role   string
+	Scopes Scopes
+}
@@ -2,25 +2,221 @@ package todo
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+const (
+	defaultReadTimeout       = 10 * time.Second
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultWriteTimeout      = 10 * time.Second
+	defaultIdleTimeout       = 60 * time.Second
+	defaultShutdownTimeout   = 20 * time.Second
+)
+
+// ServerConfig holds the timeouts of the http server. Zero values fall back
+// to the defaults above, except DrainPeriod which defaults to no draining.
+type ServerConfig struct {
+	Port              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// DrainPeriod is how long the server keeps accepting requests after
+	// shutdown began, so load balancers notice the failing readiness probe
+	// before the listener is closed.
+	DrainPeriod time.Duration
+	// ShutdownTimeout bounds waiting for in-flight requests and running the
+	// shutdown hooks once draining is over.
+	ShutdownTimeout time.Duration
+	TLS             TLSConfig
+}
+
+type shutdownHook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
 type Server struct {
+	// inFlight is first for 64-bit alignment of atomic access.
+	inFlight   int64
 	httpServer *http.Server
+	cfg        ServerConfig
+	certs      *certificates
+	stopWatch  chan struct{}
+	stopOnce   sync.Once
+
+	mu         sync.Mutex
+	drainHooks []func()
+	hooks      []shutdownHook
 }
 
-func (s Server) Run(port string, handler http.Handler) error {
+// NewServer configures the server and, when TLS is enabled, loads the
+// certificates so that a bad configuration fails at startup.
+func NewServer(cfg ServerConfig, handler http.Handler) (*Server, error) {
+	cfg.ReadTimeout = durationOr(cfg.ReadTimeout, defaultReadTimeout)
+	cfg.ReadHeaderTimeout = durationOr(cfg.ReadHeaderTimeout, defaultReadHeaderTimeout)
+	cfg.WriteTimeout = durationOr(cfg.WriteTimeout, defaultWriteTimeout)
+	cfg.IdleTimeout = durationOr(cfg.IdleTimeout, defaultIdleTimeout)
+	cfg.ShutdownTimeout = durationOr(cfg.ShutdownTimeout, defaultShutdownTimeout)
+
+	s := &Server{cfg: cfg, stopWatch: make(chan struct{})}
 	s.httpServer = &http.Server{
-		Addr:           ":" + port,
-		Handler:        handler,
-		MaxHeaderBytes: 1 << 20,
-		ReadTimeout:    10 * time.Second,
-		WriteTimeout:   10 * time.Second,
+		Addr:              ":" + cfg.Port,
+		Handler:           s.track(handler),
+		MaxHeaderBytes:    1 << 20,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+
+	if cfg.TLS.Enabled() {
+		certs, err := newCertificates(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig, err := certs.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		s.certs = certs
+		s.httpServer.TLSConfig = tlsConfig
+	}
+
+	return s, nil
+}
+
+// Run serves until Shutdown is called. It returns nil once the server was
+// shut down and an error when it could not listen or stopped on its own.
+func (s *Server) Run() error {
+	var err error
+	if s.certs != nil {
+		go s.certs.watch(s.stopWatch)
+		err = s.httpServer.ListenAndServeTLS("", "")
+	} else {
+		err = s.httpServer.ListenAndServe()
 	}
-	return s.httpServer.ListenAndServe()
+
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// ReloadCertificates reads the certificate, key and client CAs again, for
+// example on SIGHUP. New connections use them right away; on error the
+// previous certificates stay in use.
+func (s *Server) ReloadCertificates() error {
+	if s.certs == nil {
+		return errors.New("tls is not enabled")
+	}
+
+	err := s.certs.reload()
+	s.certs.notify(err)
+
+	return err
+}
+
+// TLSConfig returns the configuration https is served with, or nil when
+// TLS is disabled. It looks up the reloaded certificates per handshake, so
+// other listeners, such as the grpc server, can share it.
+func (s *Server) TLSConfig() *tls.Config {
+	return s.httpServer.TLSConfig
 }
 
+// InFlight returns the number of requests currently being served.
+func (s *Server) InFlight() int64 {
+	return atomic.LoadInt64(&s.inFlight)
+}
+
+// OnDrain registers fn to run as soon as shutdown begins, before the drain
+// period, such as failing the readiness probe.
+func (s *Server) OnDrain(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drainHooks = append(s.drainHooks, fn)
+}
+
+// OnShutdown registers fn to run after the http server stopped. Hooks run
+// in reverse order of registration, so what was started last, like
+// background workers, stops before what it depends on, like the database.
+func (s *Server) OnShutdown(name string, fn func(ctx context.Context) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, shutdownHook{name: name, fn: fn})
+}
+
+// Shutdown runs the drain hooks, keeps serving for the drain period, then
+// stops accepting connections and waits up to the shutdown timeout for
+// in-flight requests before running the shutdown hooks. Requests still
+// running at the deadline are cut off.
 func (s *Server) Shutdown(ctx context.Context) error {
-	return s.httpServer.Shutdown(ctx)
+	s.mu.Lock()
+	drainHooks := s.drainHooks
+	hooks := s.hooks
+	s.mu.Unlock()
+
+	s.stopOnce.Do(func() { close(s.stopWatch) })
+
+	for _, fn := range drainHooks {
+		fn()
+	}
+
+	if s.cfg.DrainPeriod > 0 {
+		timer := time.NewTimer(s.cfg.DrainPeriod)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.ShutdownTimeout)
+	defer cancel()
+
+	var errs []string
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Sprintf("http server: %s (%d requests cut off)", err.Error(), s.InFlight()))
+		s.httpServer.Close()
+	}
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i].fn(ctx); err != nil {
+			errs = append(errs, hooks[i].name+": "+err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New("shutdown: " + strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// track counts requests while they are served.
+func (s *Server) track(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&s.inFlight, 1)
+		defer atomic.AddInt64(&s.inFlight, -1)
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func durationOr(d, fallback time.Duration) time.Duration {
+	if d <= 0 {
+		return fallback
+	}
+
+	return d
 }
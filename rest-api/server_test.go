@@ -0,0 +1,133 @@
+package todo
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// serve starts s on a free local port and returns its base URL.
+func serve(t *testing.T, s *Server) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go s.httpServer.Serve(listener)
+	return "http://" + listener.Addr().String()
+}
+
+// slowHandler answers after delay and signals started once a request is
+// being served.
+func slowHandler(started chan<- struct{}, delay time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		time.Sleep(delay)
+		w.Write([]byte("done"))
+	})
+}
+
+func TestShutdownCompletesInFlightRequests(t *testing.T) {
+	started := make(chan struct{}, 1)
+	s, err := NewServer(ServerConfig{DrainPeriod: 20 * time.Millisecond, ShutdownTimeout: 5 * time.Second}, slowHandler(started, 200*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := serve(t, s)
+
+	var mu sync.Mutex
+	var calls []string
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, name)
+	}
+
+	s.OnDrain(func() { record("drain") })
+	for _, name := range []string{"database", "workers"} {
+		name := name
+		s.OnShutdown(name, func(ctx context.Context) error {
+			if n := s.InFlight(); n != 0 {
+				t.Errorf("hook %s ran with %d requests in flight", name, n)
+			}
+			record(name)
+			return nil
+		})
+	}
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		done <- result{status: resp.StatusCode, body: string(body), err: err}
+	}()
+
+	<-started
+	if n := s.InFlight(); n != 1 {
+		t.Fatalf("%d requests in flight, want 1", n)
+	}
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	res := <-done
+	if res.err != nil || res.status != http.StatusOK || res.body != "done" {
+		t.Fatalf("in-flight request: got %d %q, %v", res.status, res.body, res.err)
+	}
+	if n := s.InFlight(); n != 0 {
+		t.Fatalf("%d requests in flight after shutdown", n)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := strings.Join(calls, ","); got != "drain,workers,database" {
+		t.Fatalf("hooks ran as %s, want drain,workers,database", got)
+	}
+}
+
+func TestShutdownCutsOffRequestsAtTimeout(t *testing.T) {
+	started := make(chan struct{}, 1)
+	s, err := NewServer(ServerConfig{ShutdownTimeout: 50 * time.Millisecond}, slowHandler(started, time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := serve(t, s)
+
+	hookRan := false
+	s.OnShutdown("database", func(ctx context.Context) error {
+		hookRan = true
+		return nil
+	})
+
+	go func() {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+
+	err = s.Shutdown(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "1 requests cut off") {
+		t.Fatalf("got %v, want the cut off request reported", err)
+	}
+	if !hookRan {
+		t.Fatal("shutdown hooks did not run after the timeout")
+	}
+}
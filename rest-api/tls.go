@@ -0,0 +1,249 @@
+package todo
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultReloadInterval = 30 * time.Second
+
+// Cipher policies for TLS 1.2 connections. TLS 1.3 suites are not
+// configurable and are always the secure defaults of the Go runtime.
+const (
+	// CipherPolicyIntermediate allows only forward secret AEAD suites and
+	// supports clients back to TLS 1.2.
+	CipherPolicyIntermediate = "intermediate"
+	// CipherPolicyModern requires TLS 1.3.
+	CipherPolicyModern = "modern"
+)
+
+// Client authentication modes for mutual TLS. A verified client
+// certificate only admits the connection: it is not mapped to a user, so
+// requests still authenticate with a token or api key.
+const (
+	// ClientAuthOptional verifies a client certificate when one is sent,
+	// so internal callers can use certificates next to token callers.
+	ClientAuthOptional = "optional"
+	// ClientAuthRequire rejects connections without a valid certificate.
+	ClientAuthRequire = "require"
+)
+
+var intermediateCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+}
+
+// TLSConfig enables https when CertFile is set. HTTP/2 is negotiated with
+// clients that support it. The grpc server is served with the same
+// certificates through Server.TLSConfig.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// MinVersion is "1.2" or "1.3". Empty means 1.2.
+	MinVersion string
+	// CipherPolicy is CipherPolicyIntermediate or CipherPolicyModern.
+	// Empty means intermediate.
+	CipherPolicy string
+	// ClientCAFile enables mutual TLS with client certificates signed by
+	// the CAs in the file.
+	ClientCAFile string
+	// ClientAuth is ClientAuthOptional or ClientAuthRequire. Empty means
+	// optional.
+	ClientAuth string
+	// ReloadInterval is how often the files are checked for changes.
+	ReloadInterval time.Duration
+	// OnReload is called after every reload with its outcome. A failed
+	// reload keeps serving the previous certificates.
+	OnReload func(err error)
+}
+
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != ""
+}
+
+// certificates holds the current server certificate and client CAs and
+// swaps them when the files change.
+type certificates struct {
+	cfg TLSConfig
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	files     map[string]fileVersion
+}
+
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+func newCertificates(cfg TLSConfig) (*certificates, error) {
+	if cfg.KeyFile == "" {
+		return nil, errors.New("tls: key file is required with a certificate file")
+	}
+
+	c := &certificates{cfg: cfg}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// reload reads the certificate, key and client CAs. Nothing is replaced
+// unless all of them load.
+func (c *certificates) reload() error {
+	files := c.versions()
+
+	cert, err := tls.LoadX509KeyPair(c.cfg.CertFile, c.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("tls: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if c.cfg.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(c.cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("tls: %w", err)
+		}
+
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("tls: no certificates found in %s", c.cfg.ClientCAFile)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert = &cert
+	c.clientCAs = clientCAs
+	c.files = files
+
+	return nil
+}
+
+// changed reports whether any of the files was modified since the last
+// reload.
+func (c *certificates) changed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for name, version := range c.versions() {
+		if c.files[name] != version {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *certificates) versions() map[string]fileVersion {
+	versions := make(map[string]fileVersion, 3)
+	for _, name := range []string{c.cfg.CertFile, c.cfg.KeyFile, c.cfg.ClientCAFile} {
+		if name == "" {
+			continue
+		}
+		if info, err := os.Stat(name); err == nil {
+			versions[name] = fileVersion{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+
+	return versions
+}
+
+// watch reloads the certificates whenever the files change, until stop is
+// closed.
+func (c *certificates) watch(stop <-chan struct{}) {
+	interval := durationOr(c.cfg.ReloadInterval, defaultReloadInterval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if c.changed() {
+				c.notify(c.reload())
+			}
+		}
+	}
+}
+
+func (c *certificates) notify(err error) {
+	if c.cfg.OnReload != nil {
+		c.cfg.OnReload(err)
+	}
+}
+
+func (c *certificates) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+func (c *certificates) currentClientCAs() *x509.CertPool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.clientCAs
+}
+
+// tlsConfig builds the server side configuration. Certificates and client
+// CAs are looked up per handshake, so reloads apply to new connections
+// without restarting the listener.
+func (c *certificates) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		CipherSuites:   intermediateCipherSuites,
+		NextProtos:     []string{"h2", "http/1.1"},
+		GetCertificate: c.getCertificate,
+	}
+
+	switch c.cfg.MinVersion {
+	case "", "1.2":
+	case "1.3":
+		config.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("tls: unsupported minimum version %q", c.cfg.MinVersion)
+	}
+
+	switch c.cfg.CipherPolicy {
+	case "", CipherPolicyIntermediate:
+	case CipherPolicyModern:
+		config.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("tls: unknown cipher policy %q", c.cfg.CipherPolicy)
+	}
+
+	if c.cfg.ClientCAFile == "" {
+		return config, nil
+	}
+
+	switch c.cfg.ClientAuth {
+	case "", ClientAuthOptional:
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("tls: unknown client auth mode %q", c.cfg.ClientAuth)
+	}
+
+	config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		clientConfig := config.Clone()
+		clientConfig.GetConfigForClient = nil
+		clientConfig.ClientCAs = c.currentClientCAs()
+		return clientConfig, nil
+	}
+
+	return config, nil
+}
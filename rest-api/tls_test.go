@@ -0,0 +1,349 @@
+package todo
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA issues certificates for the tls tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key for name, usable by a server on
+// 127.0.0.1 or by a client.
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeTestFile(t *testing.T, name string, data []byte) {
+	t.Helper()
+
+	if err := ioutil.WriteFile(name, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testTLSFiles writes a server certificate, its key and the client CA to dir.
+func testTLSFiles(t *testing.T, dir string, ca, clientCA *testCA) TLSConfig {
+	t.Helper()
+
+	cfg := TLSConfig{
+		CertFile:     filepath.Join(dir, "server.pem"),
+		KeyFile:      filepath.Join(dir, "server-key.pem"),
+		ClientCAFile: filepath.Join(dir, "client-ca.pem"),
+	}
+
+	certPEM, keyPEM := ca.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	writeTestFile(t, cfg.CertFile, certPEM)
+	writeTestFile(t, cfg.KeyFile, keyPEM)
+	writeTestFile(t, cfg.ClientCAFile, clientCA.pem)
+
+	return cfg
+}
+
+func serverCertificate(t *testing.T, c *certificates) *x509.Certificate {
+	t.Helper()
+
+	cert, err := c.getCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return leaf
+}
+
+func TestCertificatesReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "ca")
+	cfg := testTLSFiles(t, dir, ca, ca)
+
+	certs, err := newCertificates(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := serverCertificate(t, certs)
+
+	if certs.changed() {
+		t.Fatal("changed before the files were touched")
+	}
+
+	certPEM, keyPEM := ca.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	writeTestFile(t, cfg.CertFile, certPEM)
+	writeTestFile(t, cfg.KeyFile, keyPEM)
+	later := time.Now().Add(time.Minute)
+	for _, name := range []string{cfg.CertFile, cfg.KeyFile} {
+		if err := chtimes(name, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !certs.changed() {
+		t.Fatal("not changed after the files were replaced")
+	}
+	if err := certs.reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	second := serverCertificate(t, certs)
+	if second.SerialNumber.Cmp(first.SerialNumber) == 0 {
+		t.Fatal("certificate was not replaced")
+	}
+	if certs.changed() {
+		t.Error("still changed after reload")
+	}
+
+	writeTestFile(t, cfg.KeyFile, []byte("not a key"))
+	if err := certs.reload(); err == nil {
+		t.Fatal("reload accepted a broken key")
+	}
+	if serverCertificate(t, certs).SerialNumber.Cmp(second.SerialNumber) != 0 {
+		t.Error("failed reload replaced the certificate")
+	}
+}
+
+func TestNewCertificatesFailsOnBadFiles(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "ca")
+	cfg := testTLSFiles(t, dir, ca, ca)
+
+	noCAs := cfg
+	noCAs.ClientCAFile = filepath.Join(dir, "empty.pem")
+	writeTestFile(t, noCAs.ClientCAFile, []byte("no certificates"))
+
+	missingKey := cfg
+	missingKey.KeyFile = ""
+
+	for name, cfg := range map[string]TLSConfig{"no client CAs": noCAs, "missing key": missingKey} {
+		if _, err := newCertificates(cfg); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestClientAuthModes(t *testing.T) {
+	dir := t.TempDir()
+	serverCA := newTestCA(t, "server ca")
+	clientCA := newTestCA(t, "client ca")
+	otherCA := newTestCA(t, "other ca")
+	cfg := testTLSFiles(t, dir, serverCA, clientCA)
+
+	clientCert := func(ca *testCA) []tls.Certificate {
+		certPEM, keyPEM := ca.issue(t, "client", x509.ExtKeyUsageClientAuth)
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return []tls.Certificate{cert}
+	}
+
+	tests := []struct {
+		clientAuth   string
+		certificates []tls.Certificate
+		ok           bool
+	}{
+		{"", nil, true},
+		{"", clientCert(clientCA), true},
+		{"", clientCert(otherCA), false},
+		{ClientAuthOptional, nil, true},
+		{ClientAuthOptional, clientCert(clientCA), true},
+		{ClientAuthOptional, clientCert(otherCA), false},
+		{ClientAuthRequire, nil, false},
+		{ClientAuthRequire, clientCert(clientCA), true},
+		{ClientAuthRequire, clientCert(otherCA), false},
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCA.cert)
+
+	for _, test := range tests {
+		cfg := cfg
+		cfg.ClientAuth = test.clientAuth
+
+		certs, err := newCertificates(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config, err := certs.tlsConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// GetClientCertificate sends the certificate even when the server
+		// does not list its CA, which Certificates alone would not.
+		certificates := test.certificates
+		err = handshake(t, config, &tls.Config{RootCAs: roots, ServerName: "127.0.0.1",
+			GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+				if len(certificates) == 0 {
+					return &tls.Certificate{}, nil
+				}
+				return &certificates[0], nil
+			}})
+		if ok := err == nil; ok != test.ok {
+			t.Errorf("client_auth %q with %d certificates: handshake error %v, want ok %v", test.clientAuth, len(test.certificates), err, test.ok)
+		}
+	}
+}
+
+func TestTLSConfigRejectsUnknownSettings(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "ca")
+	base := testTLSFiles(t, dir, ca, ca)
+
+	for name, change := range map[string]func(*TLSConfig){
+		"client auth":   func(c *TLSConfig) { c.ClientAuth = "maybe" },
+		"min version":   func(c *TLSConfig) { c.MinVersion = "1.1" },
+		"cipher policy": func(c *TLSConfig) { c.CipherPolicy = "legacy" },
+	} {
+		cfg := base
+		change(&cfg)
+
+		certs, err := newCertificates(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := certs.tlsConfig(); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+// handshake connects a client to a listener using server and returns the
+// error of the server side of the handshake, or of the client side when
+// the server accepted.
+func handshake(t *testing.T, server, client *tls.Config) error {
+	t.Helper()
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		defer conn.Close()
+		serverErr <- conn.(*tls.Conn).Handshake()
+	}()
+
+	conn, err := tls.Dial("tcp", listener.Addr().String(), client)
+	if err == nil {
+		// With TLS 1.3 the client finishes before the server has checked
+		// its certificate; reading surfaces the server's verdict.
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		conn.Read(make([]byte, 1))
+		conn.Close()
+	}
+
+	if err := <-serverErr; err != nil {
+		return err
+	}
+	return err
+}
+
+func chtimes(name string, t time.Time) error {
+	return os.Chtimes(name, t, t)
+}
+
+func TestServerTLSConfig(t *testing.T) {
+	s, err := NewServer(ServerConfig{}, http.NotFoundHandler())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.TLSConfig() != nil {
+		t.Error("TLSConfig without certificates is not nil")
+	}
+
+	ca := newTestCA(t, "ca")
+	s, err = NewServer(ServerConfig{TLS: testTLSFiles(t, t.TempDir(), ca, ca)}, http.NotFoundHandler())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config := s.TLSConfig(); config == nil || config.GetCertificate == nil {
+		t.Errorf("TLSConfig = %+v, want the reloading configuration", config)
+	}
+}
@@ -1,11 +1,12 @@
 package todo
 
-import "errors"
+import "akhmet.com/rest-api/pkg/validate"
 
 type TodoList struct {
-	Id          int    `json:"id" db:"id"`
-	Title       string `json:"title" db:"title" binding:"required"`
-	Description string `json:"description" db:"description"`
+	Id          int     `json:"id" db:"id"`
+	WorkspaceId int     `json:"workspace_id" db:"workspace_id"`
+	Title       string  `json:"title" db:"title" validate:"trim,required,max=255,charset=text"`
+	Description *string `json:"description" db:"description" validate:"max=255"`
 }
 
 type UserList struct {
@@ -15,10 +16,10 @@ type UserList struct {
 }
 
 type TodoItem struct {
-	Id          int    `json:"id" db:"id"`
-	Title       string `json:"title" db:"title" binding:"required"`
-	Description string `json:"description" db:"description"`
-	Done        bool   `json:"done" db:"done"`
+	Id          int     `json:"id" db:"id"`
+	Title       string  `json:"title" db:"title" validate:"trim,required,max=255,charset=text"`
+	Description *string `json:"description" db:"description" validate:"max=255"`
+	Done        bool    `json:"done" db:"done"`
 }
 
 type ListsItem struct {
@@ -28,28 +29,36 @@ type ListsItem struct {
 }
 
 type UpdateListInput struct {
-	Title       *string `json:"title"`
-	Description *string `json:"description"`
+	Title       *string `json:"title" validate:"trim,notempty,max=255,charset=text"`
+	Description *string `json:"description" validate:"max=255"`
 }
 
 type UpdateItemInput struct {
-	Title       *string `json:"title"`
-	Description *string `json:"description"`
+	Title       *string `json:"title" validate:"trim,notempty,max=255,charset=text"`
+	Description *string `json:"description" validate:"max=255"`
 	Done		*bool `json:"done"`
 }
 
-func (i UpdateListInput) Validate() error {
+func (i *UpdateListInput) Check() validate.Errors {
 	if i.Title == nil && i.Description == nil {
-		return errors.New("update structure has no values")
+		return validate.Errors{{Code: validate.CodeRequired, Message: "update structure has no values"}}
 	}
 
 	return nil
 }
 
-func (i UpdateItemInput) Validate() error {
+func (i *UpdateListInput) Validate() error {
+	return validate.Struct(i)
+}
+
+func (i *UpdateItemInput) Check() validate.Errors {
 	if i.Title == nil && i.Description == nil && i.Done == nil {
-		return errors.New("update structure has no values")
+		return validate.Errors{{Code: validate.CodeRequired, Message: "update structure has no values"}}
 	}
 
 	return nil
 }
+
+func (i *UpdateItemInput) Validate() error {
+	return validate.Struct(i)
+}
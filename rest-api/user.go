@@ -1,8 +1,45 @@
 package todo
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
-	Id       int    `json:"-" db:"id"`
-	Name     string `json:"name" binding:"required"`
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Id                    int    `json:"-" db:"id"`
+	Name                  string `json:"name" validate:"trim,required,max=255,charset=text"`
+	Username              string `json:"username" validate:"trim,required,min=3,max=64,charset=username"`
+	Password              string `json:"password" validate:"required,min=8,max=128"`
+	TOTPSecret            string `json:"-" db:"totp_secret"`
+	TOTPEnabled           bool   `json:"-" db:"totp_enabled"`
+	Role                  string `json:"-" db:"role"`
+	Disabled              bool   `json:"-" db:"disabled"`
+	PasswordResetRequired bool   `json:"-" db:"password_reset_required"`
+}
+
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// UserAccount is the view of a user exposed through the admin API.
+type UserAccount struct {
+	Id                    int    `json:"id" db:"id"`
+	Name                  string `json:"name" db:"name"`
+	Username              string `json:"username" db:"username"`
+	Role                  string `json:"role" db:"role"`
+	Disabled              bool   `json:"disabled" db:"disabled"`
+	PasswordResetRequired bool   `json:"password_reset_required" db:"password_reset_required"`
+	TOTPEnabled           bool   `json:"totp_enabled" db:"totp_enabled"`
+}
+
+type UserUsage struct {
+	UserId   int    `json:"user_id" db:"user_id"`
+	Username string `json:"username" db:"username"`
+	Lists    int    `json:"lists" db:"lists"`
+	Items    int    `json:"items" db:"items"`
+}
+
+type TransferListInput struct {
+	UserId int `json:"user_id" validate:"required"`
 }
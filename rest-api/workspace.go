@@ -0,0 +1,24 @@
+package todo
+
+const (
+	WorkspaceRoleOwner  = "owner"
+	WorkspaceRoleMember = "member"
+)
+
+type Workspace struct {
+	Id       int    `json:"id" db:"id"`
+	Name     string `json:"name" db:"name" validate:"trim,required,max=255,charset=text"`
+	Personal bool   `json:"personal" db:"personal"`
+	Role     string `json:"role" db:"role"`
+}
+
+type WorkspaceMember struct {
+	UserId   int    `json:"user_id" db:"user_id"`
+	Username string `json:"username" db:"username"`
+	Role     string `json:"role" db:"role"`
+}
+
+type AddWorkspaceMemberInput struct {
+	Username string `json:"username" validate:"trim,required"`
+	Role     string `json:"role" validate:"oneof=owner member"`
+}